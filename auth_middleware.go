@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/DIVIgor/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// Type of request context keys set by the server, so they can't collide with other packages
+type contextKey string
+
+// Context key of the authenticated user ID
+const userIDKey contextKey = "userID"
+
+// Validate access token and pass the user ID to `next` through request context.
+// Requests without a valid token are rejected with 401.
+func (cfg *apiConfig) middlewareAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		userID, err := cfg.authenticate(req)
+		if err != nil {
+			respWithAuthErr(writer, err)
+			return
+		}
+
+		next(writer, req.WithContext(context.WithValue(req.Context(), userIDKey, userID)))
+	}
+}
+
+// Same as `middlewareAuth`, but requests without Authorization header pass as anonymous.
+// A token that is present but invalid is still rejected, so clients know to refresh it.
+func (cfg *apiConfig) middlewareOptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		userID, err := cfg.authenticate(req)
+		if errors.Is(err, auth.ErrNoAuthHeader) {
+			next(writer, req)
+			return
+		}
+		if err != nil {
+			respWithAuthErr(writer, err)
+			return
+		}
+
+		next(writer, req.WithContext(context.WithValue(req.Context(), userIDKey, userID)))
+	}
+}
+
+// Reject request that failed authentication.
+// Failed revocation checks get 503, since telling clients their valid token is invalid would make them drop it.
+func respWithAuthErr(writer http.ResponseWriter, err error) {
+	if errors.Is(err, auth.ErrRevocationCheck) {
+		respWithErr(writer, http.StatusServiceUnavailable, "Couldn't validate access token", err)
+		return
+	}
+
+	respWithTokenErr(writer, err)
+}
+
+// Get user ID from the access token of the request
+func (cfg *apiConfig) authenticate(req *http.Request) (userID uuid.UUID, err error) {
+	token, err := auth.GetBearerToken(req.Header, auth.Bearer)
+	if err != nil {
+		return userID, err
+	}
+
+	return cfg.jwtKeys.ValidateJWT(req.Context(), token)
+}
+
+// Get ID of the user authenticated by `middlewareAuth`
+func requestUserID(req *http.Request) (userID uuid.UUID) {
+	userID, _ = req.Context().Value(userIDKey).(uuid.UUID)
+	return userID
+}
+
+// Get ID of the user authenticated by `middlewareOptionalAuth`. Invalid for anonymous requests.
+func optionalUserID(req *http.Request) (userID uuid.NullUUID) {
+	id, ok := req.Context().Value(userIDKey).(uuid.UUID)
+	return uuid.NullUUID{UUID: id, Valid: ok}
+}
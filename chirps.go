@@ -1,43 +1,83 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"slices"
+	"net/url"
 	"time"
 
-	"github.com/DIVIgor/chirpy/internal/auth"
 	"github.com/DIVIgor/chirpy/internal/database"
 
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID  `json:"id"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	LikeCount int64      `json:"like_count"`
+	// set only if the request has a valid JWT
+	LikedByMe *bool `json:"liked_by_me,omitempty"`
+	// set only for rechirps, which have an empty body
+	RechirpOf *RechirpOrigin `json:"rechirp_of,omitempty"`
 }
 
-// Create chirp by message and user id (for now)
-func (cfg *apiConfig) handlerCreateChirp(writer http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header, auth.Bearer)
+// Request body to create or edit a chirp
+//
+// `ParentID` is only used on creation to post a reply.
+type chirpPost struct {
+	Body     string     `json:"body"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+// Convert DB model to a parsable chirp
+func chirpFromDB(chirp database.ChirpRecord) Chirp {
+	parsed := Chirp{
+		ID:        chirp.ID,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+	}
+	if chirp.ParentID.Valid {
+		parsed.ParentID = &chirp.ParentID.UUID
+	}
+	// the rest of the original is filled by `addRechirpOrigins`
+	if chirp.RechirpOf.Valid {
+		parsed.RechirpOf = &RechirpOrigin{ChirpID: chirp.RechirpOf.UUID}
+	}
+
+	return parsed
+}
+
+// Fill data that isn't stored in chirp rows: rechirped originals and likes
+func (cfg *apiConfig) prepareChirps(ctx context.Context, chirps []Chirp, viewerID uuid.NullUUID) error {
+	err := cfg.addRechirpOrigins(ctx, chirps)
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Couldn't find JWT", err)
-		return
+		return err
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	return cfg.addLikes(ctx, chirps, viewerID)
+}
+
+// Create chirp by message and user id, optionally as a reply to another chirp
+func (cfg *apiConfig) handlerCreateChirp(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	allowed, err := cfg.canPost(req.Context(), userID)
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
 		return
 	}
-
-	type chirpPost struct {
-		Body string `json:"body"`
+	if !allowed {
+		respWithErr(writer, http.StatusForbidden, "Email is not verified", nil)
+		return
 	}
 
 	decoder := json.NewDecoder(req.Body)
@@ -51,85 +91,177 @@ func (cfg *apiConfig) handlerCreateChirp(writer http.ResponseWriter, req *http.R
 	validatedBody, err := validateChirp(data.Body)
 	if err != nil {
 		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
 	}
 
-	// save to DB
-	chirp, err := cfg.dbQueries.CreateChirp(req.Context(), database.CreateChirpParams{
-		Body:   validatedBody,
-		UserID: userID,
-	})
+	// check that the chirp being replied to exists
+	var parentID uuid.NullUUID
+	if data.ParentID != nil {
+		parent, err := cfg.dbQueries.GetChirp(req.Context(), *data.ParentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				respWithErr(writer, http.StatusBadRequest, "Parent chirp not found", err)
+			} else {
+				respWithErr(writer, http.StatusInternalServerError, "Couldn't get parent chirp", err)
+			}
+			return
+		}
+		// reply to the original chirp instead of its rechirp
+		if parent.RechirpOf.Valid {
+			parentID = parent.RechirpOf
+		} else {
+			parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+	}
+
+	// save to DB along with its hashtags and mentions
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 	if err != nil {
 		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp to DB:", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
 
-	respJSON(writer, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
+	row, err := qtx.CreateChirp(req.Context(), database.CreateChirpParams{
+		Body:     validatedBody,
+		UserID:   userID,
+		ParentID: parentID,
 	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp to DB:", err)
+		return
+	}
+	chirp := database.ChirpRecord(row)
+
+	err = saveChirpTags(req.Context(), qtx, chirp)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp tags", err)
+		return
+	}
+
+	err = saveChirpMentions(req.Context(), qtx, chirp)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp mentions", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp to DB:", err)
+		return
+	}
+
+	// a new chirp has no likes yet
+	likedByMe := false
+	created := chirpFromDB(chirp)
+	created.LikedByMe = &likedByMe
+
+	respJSON(writer, http.StatusCreated, created)
 }
 
-func parseChirps(chirps []database.Chirp) (chirpList []Chirp) {
+func parseChirps(chirps []database.ChirpRecord) (chirpList []Chirp) {
 	for _, chirp := range chirps {
-		chirpList = append(chirpList, Chirp{
-			ID:        chirp.ID,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-		})
+		chirpList = append(chirpList, chirpFromDB(chirp))
 	}
 
 	return
 }
 
-// Retrieve a full list of chirps (for now)
-func (cfg *apiConfig) handlerGetChirpList(writer http.ResponseWriter, req *http.Request) {
-	// check URL for author ID
-	authorIdStr := req.URL.Query().Get("author_id")
-
-	var chirpList []Chirp
+// Read optional `author_id` query parameter
+func parseAuthorFilter(query url.Values) (authorID uuid.NullUUID, err error) {
+	authorIdStr := query.Get("author_id")
 	if authorIdStr == "" {
-		// get full chirp list
-		chirps, err := cfg.dbQueries.GetChirps(req.Context())
-		if err != nil {
-			respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
-			return
-		}
+		return authorID, nil
+	}
 
-		chirpList = parseChirps(chirps)
-	} else {
-		// get chirp list by author
-		authorId, err := uuid.Parse(authorIdStr)
-		if err != nil {
-			respWithErr(writer, http.StatusInternalServerError, "Couldn't parse user id", err)
-			return
+	parsed, err := uuid.Parse(authorIdStr)
+	if err != nil {
+		return authorID, err
+	}
+
+	return uuid.NullUUID{UUID: parsed, Valid: true}, nil
+}
+
+// A single page of chirps with a cursor pointing to the next one
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// Form a page from chirps fetched with `limit + 1` rows.
+//
+// The extra row only signals that there is a next page and isn't returned.
+func newChirpPage(chirps []database.ChirpRecord, limit int32, sorting sortParams) (page chirpPage) {
+	page.Chirps = []Chirp{}
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		cursor := pageCursor{
+			Time:   last.CreatedAt,
+			ID:     last.ID,
+			SortBy: sorting.SortBy,
+			Desc:   sorting.Desc,
 		}
-		chirps, err := cfg.dbQueries.GetUserChirps(req.Context(), authorId)
-		if err != nil {
-			respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
-			return
+		if sorting.SortBy == sortByUpdatedAt {
+			cursor.Time = last.UpdatedAt
 		}
+		page.NextCursor = encodeCursor(cursor)
+	}
+	page.Chirps = append(page.Chirps, parseChirps(chirps)...)
+
+	return
+}
+
+// Retrieve a page of chirps and rechirps, optionally filtered by author and sorted by creation or update date
+func (cfg *apiConfig) handlerGetChirpList(writer http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
 
-		chirpList = parseChirps(chirps)
+	// check URL for sorting query parameters
+	sorting, err := parseSortParams(query, sortByCreatedAt, sortByUpdatedAt)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
 	}
 
-	// check URL for descending sorting query parameter
-	sorting := req.URL.Query().Get("sort")
-	if sorting == "desc" {
-		slices.SortFunc(chirpList, func(a, b Chirp) int {
-			if n := b.CreatedAt.Compare(a.CreatedAt); n != 0 {
-				return n
-			}
-			// if dates are equal compare by updated date
-			return b.UpdatedAt.Compare(a.UpdatedAt)
-		})
+	params := database.ListChirpsParams{
+		SortBy:   sorting.SortBy,
+		SortDesc: sorting.Desc,
+	}
+
+	// check URL for author ID
+	params.AuthorID, err = parseAuthorFilter(query)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't parse user id", err)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(query, sorting)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	// fetch one extra row to find out if there is a next page
+	params.PageLimit = limit + 1
+	if cursor != nil {
+		params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	chirps, err := cfg.dbQueries.ListChirps(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
 	}
 
-	respJSON(writer, http.StatusOK, chirpList)
+	page := newChirpPage(chirps, limit, sorting)
+	err = cfg.prepareChirps(req.Context(), page.Chirps, optionalUserID(req))
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, page)
 }
 
 // Get a single chirp by its ID parsed from URL
@@ -146,36 +278,129 @@ func (cfg *apiConfig) handlerGetChirp(writer http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	respJSON(writer, http.StatusOK, Chirp{
-		ID:        chirp.ID,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-	})
+	chirpList := []Chirp{chirpFromDB(chirp)}
+	err = cfg.prepareChirps(req.Context(), chirpList, optionalUserID(req))
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, chirpList[0])
 }
 
-// Delete chirp by its ID and owner ID
-func (cfg *apiConfig) handlerDeleteChirp(writer http.ResponseWriter, req *http.Request) {
+// Edit chirp body by its ID and owner ID, keeping the previous body as a revision
+func (cfg *apiConfig) handlerUpdateChirp(writer http.ResponseWriter, req *http.Request) {
 	postID, err := uuid.Parse(req.PathValue("chirpID"))
 	if err != nil {
 		respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
 		return
 	}
 
-	token, err := auth.GetBearerToken(req.Header, auth.Bearer)
+	userID := requestUserID(req)
+
+	decoder := json.NewDecoder(req.Body)
+	data := chirpPost{}
+	err = decoder.Decode(&data)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	validatedBody, err := validateChirp(data.Body)
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Couldn't find JWT", err)
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	// lock the chirp, so concurrent edits don't lose revisions
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// check that chirp exists and belongs to the user
+	post, err := qtx.GetChirpForUpdate(req.Context(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get chirp", err)
+		}
 		return
 	}
+	if post.UserID != userID {
+		respWithErr(writer, http.StatusForbidden, "You can't edit this chirp", nil)
+		return
+	}
+	if post.RechirpOf.Valid {
+		respWithErr(writer, http.StatusBadRequest, "Rechirps can't be edited", nil)
+		return
+	}
+
+	// keep the previous body in the edit history
+	_, err = qtx.CreateChirpRevision(req.Context(), database.CreateChirpRevisionParams{
+		ChirpID:   post.ID,
+		Body:      post.Body,
+		WrittenAt: post.UpdatedAt,
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp revision", err)
+		return
+	}
+
+	row, err := qtx.UpdateChirp(req.Context(), database.UpdateChirpParams{
+		ID:     postID,
+		UserID: userID,
+		Body:   validatedBody,
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	chirp := database.ChirpRecord(row)
+
+	err = saveChirpTags(req.Context(), qtx, chirp)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp tags", err)
+		return
+	}
+
+	err = saveChirpMentions(req.Context(), qtx, chirp)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save chirp mentions", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+
+	chirpList := []Chirp{chirpFromDB(chirp)}
+	err = cfg.prepareChirps(req.Context(), chirpList, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, chirpList[0])
+}
+
+// Delete chirp by its ID and owner ID
+func (cfg *apiConfig) handlerDeleteChirp(writer http.ResponseWriter, req *http.Request) {
+	postID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	userID := requestUserID(req)
 
-	post, err := cfg.dbQueries.DeleteChirp(req.Context(), database.DeleteChirpParams{
+	deletedID, err := cfg.dbQueries.DeleteChirp(req.Context(), database.DeleteChirpParams{
 		ID:     postID,
 		UserID: userID,
 	})
@@ -187,8 +412,8 @@ func (cfg *apiConfig) handlerDeleteChirp(writer http.ResponseWriter, req *http.R
 		}
 		return
 	}
-	// empty post means that user is not the owner of this chirp
-	if post == (database.Chirp{}) {
+	// empty ID means that user is not the owner of this chirp
+	if deletedID == uuid.Nil {
 		respWithErr(writer, http.StatusForbidden, "You can't delete this chirp", err)
 		return
 	}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error code for violated unique constraints
+const pqUniqueViolation pq.ErrorCode = "23505"
+
+// Check if DB error is caused by a duplicate value and return the violated constraint name
+func uniqueViolation(err error) (constraint string, ok bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return pqErr.Constraint, true
+	}
+
+	return constraint, false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Number of chirps loaded from DB at once while streaming an export
+const exportBatchSize int32 = 500
+
+// Login session included into account export. Token values are never exported.
+type SessionExport struct {
+	UserAgent string    `json:"user_agent"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Stream JSON archive with profile, active sessions, and all chirps of the authenticated user
+func (cfg *apiConfig) handlerExportUser(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	// load everything except chirps before writing, so errors still get a proper status code
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+
+	tokens, err := cfg.dbQueries.ListActiveRefreshTokens(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve sessions", err)
+		return
+	}
+	sessions := []SessionExport{}
+	for _, token := range tokens {
+		sessions = append(sessions, SessionExport{
+			UserAgent: token.UserAgent,
+			IPAddress: token.IpAddress,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+		})
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.json"`)
+	writer.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(writer)
+	writer.Write([]byte(`{"exported_at":`))
+	encoder.Encode(time.Now().UTC())
+	writer.Write([]byte(`,"profile":`))
+	encoder.Encode(userFromDB(user))
+	writer.Write([]byte(`,"sessions":`))
+	encoder.Encode(sessions)
+
+	// stream chirps in batches, so large accounts aren't loaded into memory at once
+	writer.Write([]byte(`,"chirps":[`))
+	params := database.ListChirpsParams{
+		AuthorID:  uuid.NullUUID{UUID: userID, Valid: true},
+		SortBy:    sortByCreatedAt,
+		PageLimit: exportBatchSize,
+	}
+	firstChirp := true
+	for {
+		chirps, err := cfg.dbQueries.ListChirps(req.Context(), params)
+		if err != nil {
+			// the status is already sent, so break the connection to not pass off a partial archive as complete
+			log.Println("Couldn't export chirps:", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		for _, chirp := range chirps {
+			if !firstChirp {
+				writer.Write([]byte(","))
+			}
+			firstChirp = false
+			encoder.Encode(chirpFromDB(chirp))
+		}
+		if flusher, ok := writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if len(chirps) < int(exportBatchSize) {
+			break
+		}
+		last := chirps[len(chirps)-1]
+		params.CursorTime = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+	writer.Write([]byte("]}"))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Timeline and follow listings are always ordered from the newest
+var newestFirst = sortParams{SortBy: sortByCreatedAt, Desc: true}
+
+// Follower or followee with the date of following
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// A single page of followers or followees with a cursor pointing to the next one
+type followPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// Form a page from follows fetched with `limit + 1` rows.
+//
+// `otherUser` picks the listed side of the follow: follower or followee.
+func newFollowPage(follows []database.Follow, limit int32, otherUser func(database.Follow) uuid.UUID) (page followPage) {
+	page.Users = []Follow{}
+	if len(follows) > int(limit) {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		page.NextCursor = encodeCursor(pageCursor{
+			Time:   last.CreatedAt,
+			ID:     otherUser(last),
+			SortBy: newestFirst.SortBy,
+			Desc:   newestFirst.Desc,
+		})
+	}
+
+	for _, follow := range follows {
+		page.Users = append(page.Users, Follow{
+			UserID:     otherUser(follow),
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+
+	return
+}
+
+// Parse user ID from URL and check that the user exists
+func (cfg *apiConfig) getPathUser(writer http.ResponseWriter, req *http.Request) (userID uuid.UUID, ok bool) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respWithErr(writer, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	_, err = cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "User not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get user", err)
+		}
+		return
+	}
+
+	return userID, true
+}
+
+// Follow or unfollow user from URL depending on `follow`
+func (cfg *apiConfig) setFollow(writer http.ResponseWriter, req *http.Request, follow bool) {
+	userID := requestUserID(req)
+
+	followeeID, ok := cfg.getPathUser(writer, req)
+	if !ok {
+		return
+	}
+	if followeeID == userID {
+		respWithErr(writer, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	var err error
+	if follow {
+		err = cfg.dbQueries.FollowUser(req.Context(), database.FollowUserParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+	} else {
+		err = cfg.dbQueries.UnfollowUser(req.Context(), database.UnfollowUserParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+	}
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update follow", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// Follow user on behalf of the authenticated user. Following twice has no effect.
+func (cfg *apiConfig) handlerFollowUser(writer http.ResponseWriter, req *http.Request) {
+	cfg.setFollow(writer, req, true)
+}
+
+// Unfollow user on behalf of the authenticated user
+func (cfg *apiConfig) handlerUnfollowUser(writer http.ResponseWriter, req *http.Request) {
+	cfg.setFollow(writer, req, false)
+}
+
+// Get a page of users following the user from URL, newest followers first
+func (cfg *apiConfig) handlerGetFollowers(writer http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.getPathUser(writer, req)
+	if !ok {
+		return
+	}
+
+	limit, cursor, err := parsePageParams(req.URL.Query(), newestFirst)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListFollowersParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if cursor != nil {
+		params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	follows, err := cfg.dbQueries.ListFollowers(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve followers", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, newFollowPage(follows, limit, func(follow database.Follow) uuid.UUID {
+		return follow.FollowerID
+	}))
+}
+
+// Get a page of users followed by the user from URL, newest followees first
+func (cfg *apiConfig) handlerGetFollowing(writer http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.getPathUser(writer, req)
+	if !ok {
+		return
+	}
+
+	limit, cursor, err := parsePageParams(req.URL.Query(), newestFirst)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListFollowingParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if cursor != nil {
+		params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	follows, err := cfg.dbQueries.ListFollowing(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve followed users", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, newFollowPage(follows, limit, func(follow database.Follow) uuid.UUID {
+		return follow.FolloweeID
+	}))
+}
+
+// Get a page of chirps from users followed by the authenticated user, newest first
+func (cfg *apiConfig) handlerGetTimeline(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	limit, cursor, err := parsePageParams(req.URL.Query(), newestFirst)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.GetTimelineParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if cursor != nil {
+		params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	chirps, err := cfg.dbQueries.GetTimeline(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve timeline", err)
+		return
+	}
+
+	page := newChirpPage(chirps, limit, newestFirst)
+	err = cfg.prepareChirps(req.Context(), page.Chirps, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, page)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Longer hashtags are most likely not meant to be tags
+const maxHashtagLen int = 50
+
+// "#" followed by letters, digits, or underscores which isn't glued to a preceding word
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// Extract unique lowercase hashtags (without "#") in order of appearance
+func extractHashtags(text string) (tags []string) {
+	seen := map[string]struct{}{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(match[1])
+		if len(tag) > maxHashtagLen {
+			continue
+		}
+		if _, exists := seen[tag]; exists {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// Clean hashtag from URL the same way as extracted ones
+func normalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		expectedTags []string
+	}{
+		{name: "No hashtags", text: "Just chirping", expectedTags: nil},
+		{name: "Start of text", text: "#golang is fun", expectedTags: []string{"golang"}},
+		{name: "Lowercased and unique", text: "#Go and #go and #GO", expectedTags: []string{"go"}},
+		{name: "Order of appearance", text: "#b #a #b", expectedTags: []string{"b", "a"}},
+		{name: "Punctuation around", text: "Love it (#chirpy), #fun!", expectedTags: []string{"chirpy", "fun"}},
+		{name: "Non-ASCII letters", text: "#café #日本", expectedTags: []string{"café", "日本"}},
+		{name: "Inside a word", text: "issue#42 and C#", expectedTags: nil},
+		{name: "HTML entity", text: "it&#39;s", expectedTags: nil},
+		{name: "Double hash", text: "##tag", expectedTags: nil},
+		{name: "Longest allowed", text: "#" + strings.Repeat("a", maxHashtagLen), expectedTags: []string{strings.Repeat("a", maxHashtagLen)}},
+		{name: "Too long", text: "#" + strings.Repeat("a", maxHashtagLen+1), expectedTags: nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			tags := extractHashtags(testCase.text)
+			if !slices.Equal(tags, testCase.expectedTags) {
+				t.Errorf("extractHashtags(%q) = %q, expected %q", testCase.text, tags, testCase.expectedTags)
+			}
+		})
+	}
+}
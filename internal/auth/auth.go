@@ -1,16 +1,16 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -21,6 +21,12 @@ const tokenTypeAccess TokenType = "chirpy-access"
 const Bearer string = "Bearer"
 const ApiBearer string = "ApiKey"
 
+// Reasons for rejecting the Authorization header
+var (
+	ErrNoAuthHeader     = errors.New("no auth header included in request")
+	ErrAuthHeaderFormat = errors.New("wrong token format")
+)
+
 // Hash password with Bcrypt
 func HashPassword(password string) (hashedPW string, err error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -42,73 +48,55 @@ func CheckPasswordHash(password, hash string) (err error) {
 	return err
 }
 
-// Create and sign JWT
+// Create and sign HS256 JWT with a shared secret
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (signedToken string, err error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(tokenTypeAccess),
-		Subject:   userID.String(),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
-	})
-
-	return token.SignedString(signingKey)
+	signedToken, _, err = NewHMACKeySet(tokenSecret).MakeJWT(userID, expiresIn)
+	return signedToken, err
 }
 
-// Validate JWT by checking token, user, and issuer
+// Validate HS256 JWT made with a shared secret
 func ValidateJWT(tokenString, tokenSecret string) (userID uuid.UUID, err error) {
-	claims := jwt.RegisteredClaims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
-	if err != nil {
-		return
-	}
-
-	userIDString, err := token.Claims.GetSubject()
-	if err != nil {
-		return
-	}
-
-	issuer, err := token.Claims.GetIssuer()
-	if err != nil {
-		return
-	}
-	if issuer != string(tokenTypeAccess) {
-		return userID, errors.New("invalid issuer")
-	}
-
-	id, err := uuid.Parse(userIDString)
-	if err != nil {
-		return userID, fmt.Errorf("invalid user ID: %w", err)
-	}
-
-	return id, err
+	return NewHMACKeySet(tokenSecret).ValidateJWT(context.Background(), tokenString)
 }
 
 // Check request headers for token and validate it. Return cleaned token string.
 func GetBearerToken(headers http.Header, bearer string) (tokenStr string, err error) {
 	token := headers.Get("Authorization")
 	if len(token) == 0 {
-		return tokenStr, errors.New("no auth header included in request")
+		return tokenStr, ErrNoAuthHeader
 	}
 
 	splittedToken := strings.Split(token, " ")
 	if len(splittedToken) < 2 || splittedToken[0] != bearer || len(splittedToken[1]) == 0 {
-		return tokenStr, errors.New("wrong token format")
+		return tokenStr, ErrAuthHeaderFormat
 	}
 
 	return splittedToken[1], err
 }
 
-// Create random 256-bit refresh token encoded in hex
-func MakeRefreshToken() (refreshToken string, err error) {
+// Create random 256-bit token encoded in hex
+func makeRandomToken() (token string, err error) {
 	key := make([]byte, 32)
 	_, err = rand.Read(key)
 	if err != nil {
-		return refreshToken, err
+		return token, err
 	}
 
 	return hex.EncodeToString(key), err
 }
+
+// Create random 256-bit refresh token encoded in hex
+func MakeRefreshToken() (refreshToken string, err error) {
+	return makeRandomToken()
+}
+
+// Create random 256-bit token for links sent by email (verification, password reset)
+func MakeOneTimeToken() (token string, err error) {
+	return makeRandomToken()
+}
+
+// Hash token with SHA-256, so it can be stored without exposing the token itself
+func HashToken(token string) (hashedToken string) {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
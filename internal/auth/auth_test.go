@@ -169,3 +169,24 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	token, _ := MakeOneTimeToken()
+	anotherToken, _ := MakeOneTimeToken()
+
+	if token == anotherToken {
+		t.Fatalf("MakeOneTimeToken() returned the same token twice")
+	}
+	if HashToken(token) != HashToken(token) {
+		t.Errorf("HashToken() isn't deterministic")
+	}
+	if HashToken(token) == HashToken(anotherToken) {
+		t.Errorf("HashToken() returned the same hash for different tokens")
+	}
+	if HashToken(token) == token {
+		t.Errorf("HashToken() returned the token itself")
+	}
+	if len(HashToken(token)) != 64 {
+		t.Errorf("HashToken() length = %d, want 64", len(HashToken(token)))
+	}
+}
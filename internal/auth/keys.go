@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Smallest RSA key accepted for signing or verification
+const minRSAKeyBits int = 2048
+
+// Audience of access tokens. Other services verifying our tokens should check it too.
+const AccessTokenAudience string = "chirpy-api"
+
+// Reasons for rejecting an access token. Errors returned by `ValidateJWT` wrap one of them.
+var (
+	ErrTokenMalformed   = errors.New("access token rejected as malformed")
+	ErrTokenSignature   = errors.New("access token rejected by signature")
+	ErrTokenExpired     = errors.New("access token rejected as expired")
+	ErrTokenNotValidYet = errors.New("access token rejected as not valid yet")
+	ErrTokenIssuer      = errors.New("access token rejected by issuer")
+	ErrTokenAudience    = errors.New("access token rejected by audience")
+	ErrTokenRevoked     = errors.New("access token rejected as revoked")
+)
+
+// Revocation store failed, so the token could be neither accepted nor rejected
+var ErrRevocationCheck = errors.New("couldn't check access token revocation")
+
+// Store of revoked access tokens, identified by the `jti` claim
+type RevocationStore interface {
+	IsRevoked(ctx context.Context, tokenID uuid.UUID) (revoked bool, err error)
+}
+
+// Key used to sign or verify JWTs. Keys without a private part can only verify.
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   crypto.PrivateKey
+	verifyKey crypto.PublicKey
+}
+
+// Set of JWT keys.
+//
+// Tokens are signed with the active key and verified with the key named by their `kid` header.
+// Keeping retired keys in the set lets tokens signed before a rotation stay valid until they expire.
+type KeySet struct {
+	active      *jwtKey
+	keys        map[string]*jwtKey
+	leeway      time.Duration
+	revocations RevocationStore
+}
+
+// Public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Public keys served to other services for token verification
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Create key set that signs and verifies HS256 tokens without `kid` with a shared secret
+func NewHMACKeySet(secret string) *KeySet {
+	keySet := &KeySet{keys: map[string]*jwtKey{}}
+	keySet.AddLegacySecret(secret)
+	keySet.active = keySet.keys[""]
+
+	return keySet
+}
+
+// Load PEM keys from `dir`, one key per `<kid>.pem` file, and sign tokens with `activeKID`.
+//
+// Private keys (RSA or Ed25519) can sign and verify, public keys can only verify.
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &KeySet{keys: map[string]*jwtKey{}}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load key %s: %w", kid, err)
+		}
+		keySet.keys[kid] = key
+	}
+
+	active, ok := keySet.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", activeKID, dir)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeKID)
+	}
+	keySet.active = active
+
+	return keySet, nil
+}
+
+// Accept HS256 tokens without `kid` signed with `secret`,
+// e.g. tokens issued before switching to asymmetric keys
+func (keySet *KeySet) AddLegacySecret(secret string) {
+	keySet.keys[""] = &jwtKey{
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// Allow clock difference with the token issuer when checking `exp`, `nbf`, and `iat` claims
+func (keySet *KeySet) SetLeeway(leeway time.Duration) {
+	keySet.leeway = leeway
+}
+
+// Reject tokens found in `store`. Tokens without `jti` are rejected too, since they can't be revoked.
+func (keySet *KeySet) SetRevocationStore(store RevocationStore) {
+	keySet.revocations = store
+}
+
+// Parse a single PEM block with PKCS #8, PKCS #1, or PKIX encoded key
+func parsePEMKey(kid string, data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{id: kid}
+	switch parsedKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, parsedKey, &parsedKey.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.verifyKey = jwt.SigningMethodRS256, parsedKey
+	case ed25519.PrivateKey:
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, parsedKey, parsedKey.Public()
+	case ed25519.PublicKey:
+		key.method, key.verifyKey = jwt.SigningMethodEdDSA, parsedKey
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if rsaKey, ok := key.verifyKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+	}
+
+	return key, nil
+}
+
+// Create and sign JWT with the active key. The returned ID is set as `jti` claim for revocation.
+func (keySet *KeySet) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (signedToken string, tokenID uuid.UUID, err error) {
+	tokenID = uuid.New()
+	token := jwt.NewWithClaims(keySet.active.method, jwt.RegisteredClaims{
+		ID:        tokenID.String(),
+		Issuer:    string(tokenTypeAccess),
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{AccessTokenAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
+	})
+	if keySet.active.id != "" {
+		token.Header["kid"] = keySet.active.id
+	}
+
+	signedToken, err = token.SignedString(keySet.active.signKey)
+	return signedToken, tokenID, err
+}
+
+// Validate JWT by checking signature, expiration, issuer, audience, user, and revocation.
+//
+// The key is picked by `kid` and must match the algorithm of the token.
+// Only algorithms of the keys in the set are accepted.
+func (keySet *KeySet) ValidateJWT(ctx context.Context, tokenString string) (userID uuid.UUID, err error) {
+	claims := jwt.RegisteredClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keySet.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+		// a public key must never be accepted as an HMAC secret and vice versa
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return key.verifyKey, nil
+	},
+		jwt.WithValidMethods(keySet.methods()),
+		jwt.WithIssuer(string(tokenTypeAccess)),
+		jwt.WithAudience(AccessTokenAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(keySet.leeway),
+	)
+	if err != nil {
+		return userID, classifyTokenError(err)
+	}
+
+	userIDString, err := token.Claims.GetSubject()
+	if err != nil {
+		return userID, fmt.Errorf("%w: %w", ErrTokenMalformed, err)
+	}
+
+	id, err := uuid.Parse(userIDString)
+	if err != nil {
+		return userID, fmt.Errorf("%w: invalid user ID: %w", ErrTokenMalformed, err)
+	}
+
+	if keySet.revocations != nil {
+		tokenID, err := uuid.Parse(claims.ID)
+		if err != nil {
+			return userID, fmt.Errorf("%w: invalid token ID: %w", ErrTokenMalformed, err)
+		}
+		revoked, err := keySet.revocations.IsRevoked(ctx, tokenID)
+		if err != nil {
+			return userID, fmt.Errorf("%w: %w", ErrRevocationCheck, err)
+		}
+		if revoked {
+			return userID, ErrTokenRevoked
+		}
+	}
+
+	return id, nil
+}
+
+// Names of signing algorithms used by the keys of the set
+func (keySet *KeySet) methods() (methods []string) {
+	for _, key := range keySet.keys {
+		if !slices.Contains(methods, key.method.Alg()) {
+			methods = append(methods, key.method.Alg())
+		}
+	}
+
+	return methods
+}
+
+// Wrap error of the JWT library into one of the token errors of the package
+func classifyTokenError(err error) error {
+	var reason error
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		reason = ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		reason = ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		reason = ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		reason = ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		reason = ErrTokenSignature
+	default:
+		reason = ErrTokenMalformed
+	}
+
+	return fmt.Errorf("%w: %w", reason, err)
+}
+
+// Public parts of asymmetric keys sorted by key ID. Shared secrets are never exposed.
+func (keySet *KeySet) JWKS() (jwks JWKS) {
+	jwks.Keys = []JWK{}
+	for _, key := range keySet.keys {
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	slices.SortFunc(jwks.Keys, func(a, b JWK) int {
+		return strings.Compare(a.Kid, b.Kid)
+	})
+
+	return jwks
+}
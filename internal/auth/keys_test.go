@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Write key to `dir` as `<kid>.pem`, either as PKCS #8 private key or as PKIX public key
+func writePEMKey(t *testing.T, dir, kid string, key any, public bool) {
+	t.Helper()
+
+	blockType := "PRIVATE KEY"
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	if public {
+		blockType = "PUBLIC KEY"
+		data, err = x509.MarshalPKIXPublicKey(key)
+	}
+	if err != nil {
+		t.Fatalf("couldn't marshal key %s: %v", kid, err)
+	}
+
+	encoded := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data})
+	err = os.WriteFile(filepath.Join(dir, kid+".pem"), encoded, 0o600)
+	if err != nil {
+		t.Fatalf("couldn't write key %s: %v", kid, err)
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, retiredKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writePEMKey(t, dir, "rsa-1", rsaKey, false)
+	writePEMKey(t, dir, "ed-1", edKey, false)
+	writePEMKey(t, dir, "ed-0", retiredKey.Public(), true)
+
+	userID := uuid.New()
+	for _, kid := range []string{"rsa-1", "ed-1"} {
+		t.Run(kid, func(t *testing.T) {
+			keySet, err := LoadKeySet(dir, kid)
+			if err != nil {
+				t.Fatalf("LoadKeySet() error = %v", err)
+			}
+
+			token, _, err := keySet.MakeJWT(userID, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+			gotUserID, err := keySet.ValidateJWT(context.Background(), token)
+			if err != nil || gotUserID != userID {
+				t.Errorf("ValidateJWT() = %v, %v, want %v", gotUserID, err, userID)
+			}
+		})
+	}
+
+	// tokens signed with the old key stay valid after rotation
+	oldDir := t.TempDir()
+	writePEMKey(t, oldDir, "ed-0", retiredKey, false)
+	oldKeySet, err := LoadKeySet(oldDir, "ed-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _, err := oldKeySet.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet, err := LoadKeySet(dir, "ed-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keySet.ValidateJWT(context.Background(), oldToken); err != nil {
+		t.Errorf("ValidateJWT() of a token signed with a retired key error = %v", err)
+	}
+
+	// HMAC tokens are rejected unless a legacy secret is configured
+	hmacToken, _ := MakeJWT(userID, "secret", time.Hour)
+	if _, err := keySet.ValidateJWT(context.Background(), hmacToken); err == nil {
+		t.Errorf("ValidateJWT() accepted HS256 token without a legacy secret")
+	}
+	keySet.AddLegacySecret("secret")
+	if _, err := keySet.ValidateJWT(context.Background(), hmacToken); err != nil {
+		t.Errorf("ValidateJWT() of a legacy HS256 token error = %v", err)
+	}
+
+	// verification-only keys can't be active
+	if _, err := LoadKeySet(dir, "ed-0"); err == nil {
+		t.Errorf("LoadKeySet() accepted a public key as the active key")
+	}
+	if _, err := LoadKeySet(dir, "missing"); err == nil {
+		t.Errorf("LoadKeySet() accepted a missing active key")
+	}
+
+	jwks := keySet.JWKS()
+	if len(jwks.Keys) != 3 {
+		t.Fatalf("JWKS() returned %d keys, want 3", len(jwks.Keys))
+	}
+	wantKeys := []struct{ kid, kty, alg string }{
+		{"ed-0", "OKP", "EdDSA"},
+		{"ed-1", "OKP", "EdDSA"},
+		{"rsa-1", "RSA", "RS256"},
+	}
+	for i, want := range wantKeys {
+		got := jwks.Keys[i]
+		if got.Kid != want.kid || got.Kty != want.kty || got.Alg != want.alg {
+			t.Errorf("JWKS() key %d = %s/%s/%s, want %s/%s/%s", i, got.Kid, got.Kty, got.Alg, want.kid, want.kty, want.alg)
+		}
+	}
+}
+
+func TestLoadKeySetRejectsWeakRSA(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writePEMKey(t, dir, "weak", weakKey, false)
+	if _, err := LoadKeySet(dir, "weak"); err == nil {
+		t.Errorf("LoadKeySet() accepted a 1024-bit RSA key")
+	}
+}
+
+func TestValidateJWTErrors(t *testing.T) {
+	userID := uuid.New()
+	keySet := NewHMACKeySet("secret")
+	now := time.Now().UTC()
+
+	// sign claims with the key set secret, bypassing MakeJWT
+	sign := func(method jwt.SigningMethod, claims jwt.RegisteredClaims) string {
+		t.Helper()
+		key := any([]byte("secret"))
+		if method == jwt.SigningMethodNone {
+			key = jwt.UnsafeAllowNoneSignatureType
+		}
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	validClaims := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    string(tokenTypeAccess),
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{AccessTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}
+	}
+
+	expiredClaims := validClaims()
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second))
+	wrongIssuerClaims := validClaims()
+	wrongIssuerClaims.Issuer = "someone-else"
+	wrongAudienceClaims := validClaims()
+	wrongAudienceClaims.Audience = jwt.ClaimStrings{"another-api"}
+	noExpirationClaims := validClaims()
+	noExpirationClaims.ExpiresAt = nil
+	wrongSecretToken, _ := MakeJWT(userID, "wrong_secret", time.Hour)
+
+	tests := []struct {
+		name    string
+		token   string
+		leeway  time.Duration
+		wantErr error
+	}{
+		{
+			name:  "Valid token",
+			token: sign(jwt.SigningMethodHS256, validClaims()),
+		},
+		{
+			name:    "Expired token",
+			token:   sign(jwt.SigningMethodHS256, expiredClaims),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:   "Expired token within leeway",
+			token:  sign(jwt.SigningMethodHS256, expiredClaims),
+			leeway: time.Minute,
+		},
+		{
+			name:    "Wrong secret",
+			token:   wrongSecretToken,
+			wantErr: ErrTokenSignature,
+		},
+		{
+			name:    "Unpinned algorithm",
+			token:   sign(jwt.SigningMethodHS512, validClaims()),
+			wantErr: ErrTokenSignature,
+		},
+		{
+			name:    "Unsigned token",
+			token:   sign(jwt.SigningMethodNone, validClaims()),
+			wantErr: ErrTokenSignature,
+		},
+		{
+			name:    "Wrong issuer",
+			token:   sign(jwt.SigningMethodHS256, wrongIssuerClaims),
+			wantErr: ErrTokenIssuer,
+		},
+		{
+			name:    "Wrong audience",
+			token:   sign(jwt.SigningMethodHS256, wrongAudienceClaims),
+			wantErr: ErrTokenAudience,
+		},
+		{
+			name:    "No expiration",
+			token:   sign(jwt.SigningMethodHS256, noExpirationClaims),
+			wantErr: ErrTokenMalformed,
+		},
+		{
+			name:    "Garbage",
+			token:   "invalid.token.string",
+			wantErr: ErrTokenMalformed,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			keySet.SetLeeway(testCase.leeway)
+			gotUserID, err := keySet.ValidateJWT(context.Background(), testCase.token)
+			if testCase.wantErr == nil {
+				if err != nil || gotUserID != userID {
+					t.Errorf("ValidateJWT() = %v, %v, want %v", gotUserID, err, userID)
+				}
+				return
+			}
+			if !errors.Is(err, testCase.wantErr) {
+				t.Errorf("ValidateJWT() error = %v, want %v", err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+// Revocation store backed by a map
+type mapRevocationStore map[uuid.UUID]bool
+
+func (store mapRevocationStore) IsRevoked(ctx context.Context, tokenID uuid.UUID) (bool, error) {
+	return store[tokenID], nil
+}
+
+func TestValidateJWTRevocation(t *testing.T) {
+	userID := uuid.New()
+	keySet := NewHMACKeySet("secret")
+	store := mapRevocationStore{}
+	keySet.SetRevocationStore(store)
+
+	token, tokenID, err := keySet.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keySet.ValidateJWT(context.Background(), token); err != nil {
+		t.Fatalf("ValidateJWT() error = %v", err)
+	}
+
+	store[tokenID] = true
+	if _, err := keySet.ValidateJWT(context.Background(), token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateJWT() of a revoked token error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// tokens without jti can't be revoked, so they aren't accepted at all
+	noIDToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(tokenTypeAccess),
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{AccessTokenAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keySet.ValidateJWT(context.Background(), noIDToken); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("ValidateJWT() of a token without jti error = %v, want %v", err, ErrTokenMalformed)
+	}
+}
+
+// Revocation store that is always unavailable
+type failingRevocationStore struct{}
+
+func (failingRevocationStore) IsRevoked(ctx context.Context, tokenID uuid.UUID) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestValidateJWTRevocationCheckFailure(t *testing.T) {
+	keySet := NewHMACKeySet("secret")
+	keySet.SetRevocationStore(failingRevocationStore{})
+
+	token, _, err := keySet.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = keySet.ValidateJWT(context.Background(), token)
+	if !errors.Is(err, ErrRevocationCheck) {
+		t.Errorf("ValidateJWT() error = %v, want %v", err, ErrRevocationCheck)
+	}
+	// a store outage must not look like a rejected token
+	for _, tokenErr := range []error{ErrTokenMalformed, ErrTokenSignature, ErrTokenExpired, ErrTokenRevoked} {
+		if errors.Is(err, tokenErr) {
+			t.Errorf("ValidateJWT() error = %v, shouldn't be %v", err, tokenErr)
+		}
+	}
+}
@@ -7,30 +7,83 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, body, user_id, created_at, updated_at)
-VALUES (gen_random_uuid(), $1, $2, NOW(), NOW())
-RETURNING id, body, user_id, created_at, updated_at
+INSERT INTO chirps(id, body, user_id, parent_id, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
+RETURNING id, body, user_id, created_at, updated_at, parent_id, rechirp_of
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body     string
+	UserID   uuid.UUID
+	ParentID uuid.NullUUID
+}
+
+type CreateChirpRow struct {
+	ID        uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ParentID  uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (CreateChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.ParentID)
+	var i CreateChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+		&i.RechirpOf,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps(id, body, user_id, rechirp_of, created_at, updated_at)
+VALUES (gen_random_uuid(), '', $1, $2, NOW(), NOW())
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+type CreateRechirpRow struct {
+	ID        uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ParentID  uuid.NullUUID
+	RechirpOf uuid.NullUUID
 }
 
-func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
-	var i Chirp
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (CreateRechirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
+	var i CreateRechirpRow
 	err := row.Scan(
 		&i.ID,
 		&i.Body,
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ParentID,
+		&i.RechirpOf,
 	)
 	return i, err
 }
@@ -39,7 +92,7 @@ const deleteChirp = `-- name: DeleteChirp :one
 DELETE
 FROM chirps
 WHERE id = $1 AND user_id = $2
-RETURNING id, body, user_id, created_at, updated_at
+RETURNING id
 `
 
 type DeleteChirpParams struct {
@@ -47,59 +100,98 @@ type DeleteChirpParams struct {
 	UserID uuid.UUID
 }
 
-func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) (Chirp, error) {
+func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) (uuid.UUID, error) {
 	row := q.db.QueryRowContext(ctx, deleteChirp, arg.ID, arg.UserID)
-	var i Chirp
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const deleteRechirp = `-- name: DeleteRechirp :one
+DELETE
+FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2
+RETURNING id
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getChirp = `-- name: GetChirp :one
+SELECT id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+FROM chirp_records
+WHERE ID = $1
+`
+
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (ChirpRecord, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, id)
+	var i ChirpRecord
 	err := row.Scan(
 		&i.ID,
 		&i.Body,
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ParentID,
+		&i.RechirpOf,
 	)
 	return i, err
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, body, user_id, created_at, updated_at
-FROM chirps
-WHERE ID = $1
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+FROM chirp_records
+WHERE id = $1
+FOR UPDATE
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
-	var i Chirp
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (ChirpRecord, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i ChirpRecord
 	err := row.Scan(
 		&i.ID,
 		&i.Body,
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ParentID,
+		&i.RechirpOf,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, body, user_id, created_at, updated_at
-FROM chirps
-ORDER BY created_at
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+FROM chirp_records
+WHERE id = ANY($1::UUID[])
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]ChirpRecord, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []ChirpRecord
 	for rows.Next() {
-		var i Chirp
+		var i ChirpRecord
 		if err := rows.Scan(
 			&i.ID,
 			&i.Body,
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ParentID,
+			&i.RechirpOf,
 		); err != nil {
 			return nil, err
 		}
@@ -114,28 +206,47 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getUserChirps = `-- name: GetUserChirps :many
-SELECT id, body, user_id, created_at, updated_at
-FROM chirps
-WHERE user_id = $1
-ORDER BY created_at
+const getChirpThread = `-- name: GetChirpThread :many
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.id, chirps.parent_id
+    FROM chirps
+    WHERE chirps.id = $1
+    UNION ALL
+    SELECT chirps.id, chirps.parent_id
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.parent_id
+), thread AS (
+    SELECT ancestors.id
+    FROM ancestors
+    WHERE ancestors.parent_id IS NULL
+    UNION ALL
+    SELECT chirps.id
+    FROM chirps
+    JOIN thread ON chirps.parent_id = thread.id
+)
+SELECT chirp_records.id, chirp_records.body, chirp_records.user_id, chirp_records.created_at, chirp_records.updated_at, chirp_records.parent_id, chirp_records.rechirp_of
+FROM chirp_records
+WHERE chirp_records.id IN (SELECT thread.id FROM thread)
+ORDER BY chirp_records.created_at, chirp_records.id
 `
 
-func (q *Queries) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getUserChirps, userID)
+func (q *Queries) GetChirpThread(ctx context.Context, id uuid.UUID) ([]ChirpRecord, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpThread, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []ChirpRecord
 	for rows.Next() {
-		var i Chirp
+		var i ChirpRecord
 		if err := rows.Scan(
 			&i.ID,
 			&i.Body,
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ParentID,
+			&i.RechirpOf,
 		); err != nil {
 			return nil, err
 		}
@@ -149,3 +260,192 @@ func (q *Queries) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp,
 	}
 	return items, nil
 }
+
+const listChirps = `-- name: ListChirps :many
+SELECT id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+FROM chirp_records
+WHERE ($1::UUID IS NULL OR user_id = $1)
+    AND ($2::TIMESTAMP IS NULL
+        OR (NOT $3::BOOLEAN
+            AND (CASE WHEN $4::TEXT = 'updated_at' THEN updated_at ELSE created_at END, id)
+                > ($2, $5::UUID))
+        OR ($3::BOOLEAN
+            AND (CASE WHEN $4::TEXT = 'updated_at' THEN updated_at ELSE created_at END, id)
+                < ($2, $5::UUID)))
+ORDER BY
+    CASE WHEN $3::BOOLEAN AND $4::TEXT = 'updated_at' THEN updated_at END DESC,
+    CASE WHEN $3::BOOLEAN AND $4::TEXT <> 'updated_at' THEN created_at END DESC,
+    CASE WHEN $3::BOOLEAN THEN id END DESC,
+    CASE WHEN NOT $3::BOOLEAN AND $4::TEXT = 'updated_at' THEN updated_at END,
+    CASE WHEN NOT $3::BOOLEAN AND $4::TEXT <> 'updated_at' THEN created_at END,
+    id
+LIMIT $6
+`
+
+type ListChirpsParams struct {
+	AuthorID   uuid.NullUUID
+	CursorTime sql.NullTime
+	SortDesc   bool
+	SortBy     string
+	CursorID   uuid.NullUUID
+	PageLimit  int32
+}
+
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]ChirpRecord, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps,
+		arg.AuthorID,
+		arg.CursorTime,
+		arg.SortDesc,
+		arg.SortBy,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpRecord
+	for rows.Next() {
+		var i ChirpRecord
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ParentID,
+			&i.RechirpOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirp_records.id, chirp_records.body, chirp_records.user_id, chirp_records.created_at, chirp_records.updated_at, chirp_records.parent_id, chirp_records.rechirp_of, matches.sort_key
+FROM chirp_records
+JOIN (
+    SELECT id,
+        (CASE $1::TEXT
+            WHEN 'created_at' THEN EXTRACT(EPOCH FROM created_at)
+            WHEN 'updated_at' THEN EXTRACT(EPOCH FROM updated_at)
+            ELSE ts_rank(search_vector, websearch_to_tsquery('english', $2::TEXT))
+        END)::DOUBLE PRECISION AS sort_key
+    FROM chirps
+    WHERE search_vector @@ websearch_to_tsquery('english', $2::TEXT)
+        AND ($3::UUID IS NULL OR user_id = $3)
+) AS matches ON chirp_records.id = matches.id
+WHERE $4::DOUBLE PRECISION IS NULL
+    OR (NOT $5::BOOLEAN
+        AND (matches.sort_key, chirp_records.id) > ($4, $6::UUID))
+    OR ($5::BOOLEAN
+        AND (matches.sort_key, chirp_records.id) < ($4, $6::UUID))
+ORDER BY
+    CASE WHEN $5::BOOLEAN THEN matches.sort_key END DESC,
+    CASE WHEN $5::BOOLEAN THEN chirp_records.id END DESC,
+    matches.sort_key,
+    chirp_records.id
+LIMIT $7
+`
+
+type SearchChirpsParams struct {
+	SortBy    string
+	Query     string
+	AuthorID  uuid.NullUUID
+	CursorKey sql.NullFloat64
+	SortDesc  bool
+	CursorID  uuid.NullUUID
+	PageLimit int32
+}
+
+type SearchChirpsRow struct {
+	ChirpRecord ChirpRecord
+	SortKey     float64
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.SortBy,
+		arg.Query,
+		arg.AuthorID,
+		arg.CursorKey,
+		arg.SortDesc,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ChirpRecord.ID,
+			&i.ChirpRecord.Body,
+			&i.ChirpRecord.UserID,
+			&i.ChirpRecord.CreatedAt,
+			&i.ChirpRecord.UpdatedAt,
+			&i.ChirpRecord.ParentID,
+			&i.ChirpRecord.RechirpOf,
+			&i.SortKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $3,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+`
+
+type UpdateChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Body   string
+}
+
+type UpdateChirpRow struct {
+	ID        uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ParentID  uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (UpdateChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.ID, arg.UserID, arg.Body)
+	var i UpdateChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+		&i.RechirpOf,
+	)
+	return i, err
+}
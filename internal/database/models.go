@@ -12,27 +12,114 @@ import (
 )
 
 type Chirp struct {
+	ID           uuid.UUID
+	Body         string
+	UserID       uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ParentID     uuid.NullUUID
+	RechirpOf    uuid.NullUUID
+	SearchVector interface{}
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRecord struct {
 	ID        uuid.UUID
 	Body      string
 	UserID    uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	ParentID  uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	Body      string
+	WrittenAt time.Time
+	CreatedAt time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginThrottle struct {
+	Subject      string
+	Failures     int32
+	BlockedUntil time.Time
+	UpdatedAt    time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
-	Token     string
+	TokenHash       string
+	UserID          uuid.UUID
+	ExpiresAt       time.Time
+	RevokedAt       sql.NullTime
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	FamilyID        uuid.UUID
+	ParentTokenHash sql.NullString
+	RotatedAt       sql.NullTime
+	UserAgent       string
+	IpAddress       string
+	AccessTokenID   uuid.NullUUID
+	AccessExpiresAt sql.NullTime
+}
+
+type RevokedAccessToken struct {
+	TokenID   uuid.UUID
 	UserID    uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
 	CreatedAt time.Time
-	UpdatedAt time.Time
 }
 
 type User struct {
-	ID             uuid.UUID
-	Email          string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	HashedPassword string
-	IsChirpyRed    bool
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	HashedPassword  string
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	DisplayName     string
+	Bio             string
+	EmailVerifiedAt sql.NullTime
 }
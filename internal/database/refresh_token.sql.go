@@ -7,69 +7,343 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-const getUserFromToken = `-- name: GetUserFromToken :one
-SELECT users.id, users.email, users.created_at, users.updated_at, users.hashed_password, users.is_chirpy_red
-FROM users
-JOIN refresh_tokens
-ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1
+const getRefreshToken = `-- name: GetRefreshToken :one
+SELECT token_hash, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token_hash, rotated_at, user_agent, ip_address, access_token_id, access_expires_at
+FROM refresh_tokens
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FamilyID,
+		&i.ParentTokenHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessExpiresAt,
+	)
+	return i, err
+}
+
+const listActiveRefreshTokens = `-- name: ListActiveRefreshTokens :many
+SELECT token_hash, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token_hash, rotated_at, user_agent, ip_address, access_token_id, access_expires_at
+FROM refresh_tokens
+WHERE user_id = $1
     AND revoked_at IS NULL
     AND expires_at > NOW()
+ORDER BY created_at
+`
+
+func (q *Queries) ListActiveRefreshTokens(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveRefreshTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.TokenHash,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FamilyID,
+			&i.ParentTokenHash,
+			&i.RotatedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.AccessTokenID,
+			&i.AccessExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSessions = `-- name: ListSessions :many
+SELECT refresh_tokens.family_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip_address,
+    logins.created_at,
+    refresh_tokens.created_at AS refreshed_at,
+    refresh_tokens.expires_at
+FROM refresh_tokens
+JOIN refresh_tokens AS logins
+ON logins.family_id = refresh_tokens.family_id
+    AND logins.parent_token_hash IS NULL
+WHERE refresh_tokens.user_id = $1
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+ORDER BY logins.created_at DESC
+`
+
+type ListSessionsRow struct {
+	FamilyID    uuid.UUID
+	UserAgent   string
+	IpAddress   string
+	CreatedAt   time.Time
+	RefreshedAt time.Time
+	ExpiresAt   time.Time
+}
+
+func (q *Queries) ListSessions(ctx context.Context, userID uuid.UUID) ([]ListSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSessionsRow
+	for rows.Next() {
+		var i ListSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.RefreshedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeSession = `-- name: RevokeSession :many
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, NOW()),
+    updated_at = NOW()
+WHERE family_id = $1
+    AND user_id = $2
+    AND (revoked_at IS NULL OR access_expires_at > NOW())
+RETURNING access_token_id, access_expires_at
+`
+
+type RevokeSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+type RevokeSessionRow struct {
+	AccessTokenID   uuid.NullUUID
+	AccessExpiresAt sql.NullTime
+}
+
+// tokens revoked earlier are included while their access token is still valid
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) ([]RevokeSessionRow, error) {
+	rows, err := q.db.QueryContext(ctx, revokeSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RevokeSessionRow
+	for rows.Next() {
+		var i RevokeSessionRow
+		if err := rows.Scan(&i.AccessTokenID, &i.AccessExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeToken = `-- name: RevokeToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
+RETURNING token_hash, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token_hash, rotated_at, user_agent, ip_address, access_token_id, access_expires_at
 `
 
-func (q *Queries) GetUserFromToken(ctx context.Context, token string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromToken, token)
-	var i User
+func (q *Queries) RevokeToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, revokeToken, tokenHash)
+	var i RefreshToken
 	err := row.Scan(
-		&i.ID,
-		&i.Email,
+		&i.TokenHash,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.FamilyID,
+		&i.ParentTokenHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessExpiresAt,
 	)
 	return i, err
 }
 
-const revokeToken = `-- name: RevokeToken :exec
+const revokeTokenFamily = `-- name: RevokeTokenFamily :many
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, NOW()),
+    updated_at = NOW()
+WHERE family_id = $1
+    AND (revoked_at IS NULL OR access_expires_at > NOW())
+RETURNING user_id, access_token_id, access_expires_at
+`
+
+type RevokeTokenFamilyRow struct {
+	UserID          uuid.UUID
+	AccessTokenID   uuid.NullUUID
+	AccessExpiresAt sql.NullTime
+}
+
+// tokens revoked earlier are included while their access token is still valid
+func (q *Queries) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) ([]RevokeTokenFamilyRow, error) {
+	rows, err := q.db.QueryContext(ctx, revokeTokenFamily, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RevokeTokenFamilyRow
+	for rows.Next() {
+		var i RevokeTokenFamilyRow
+		if err := rows.Scan(&i.UserID, &i.AccessTokenID, &i.AccessExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1
+WHERE user_id = $1
+    AND revoked_at IS NULL
 `
 
-func (q *Queries) RevokeToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeToken, token)
+func (q *Queries) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, userID)
 	return err
 }
 
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    rotated_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+RETURNING token_hash, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token_hash, rotated_at, user_agent, ip_address, access_token_id, access_expires_at
+`
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FamilyID,
+		&i.ParentTokenHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessExpiresAt,
+	)
+	return i, err
+}
+
 const saveRefreshToken = `-- name: SaveRefreshToken :one
-INSERT INTO refresh_tokens(token, user_id, expires_at, created_at, updated_at)
-VALUES ($1, $2, $3, NOW(), NOW())
-RETURNING token, user_id, expires_at, revoked_at, created_at, updated_at
+INSERT INTO refresh_tokens(token_hash, user_id, family_id, parent_token_hash, user_agent, ip_address, access_token_id, access_expires_at, expires_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+RETURNING token_hash, user_id, expires_at, revoked_at, created_at, updated_at, family_id, parent_token_hash, rotated_at, user_agent, ip_address, access_token_id, access_expires_at
 `
 
 type SaveRefreshTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	TokenHash       string
+	UserID          uuid.UUID
+	FamilyID        uuid.UUID
+	ParentTokenHash sql.NullString
+	UserAgent       string
+	IpAddress       string
+	AccessTokenID   uuid.NullUUID
+	AccessExpiresAt sql.NullTime
+	ExpiresAt       time.Time
 }
 
 func (q *Queries) SaveRefreshToken(ctx context.Context, arg SaveRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, saveRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, saveRefreshToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.FamilyID,
+		arg.ParentTokenHash,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.AccessTokenID,
+		arg.AccessExpiresAt,
+		arg.ExpiresAt,
+	)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.FamilyID,
+		&i.ParentTokenHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.AccessTokenID,
+		&i.AccessExpiresAt,
 	)
 	return i, err
 }
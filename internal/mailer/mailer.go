@@ -0,0 +1,185 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mailer kinds accepted by `New`
+const (
+	KindLog  string = "log"
+	KindFile string = "file"
+	KindSMTP string = "smtp"
+)
+
+// Mailer settings. Only the fields used by the chosen kind have to be set.
+type Config struct {
+	Kind         string
+	Dir          string // directory of the file mailer
+	SMTPAddr     string // host:port of the SMTP server
+	SMTPUsername string // no authentication if empty
+	SMTPPassword string
+	From         string // sender address of SMTP emails
+}
+
+// Plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Delivers emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Print emails to the server log. Meant for development only.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// Save every email to a separate file in `Dir`. Meant for development and tests.
+type FileMailer struct {
+	Dir string
+}
+
+func (mailer FileMailer) Send(ctx context.Context, msg Message) error {
+	err := os.MkdirAll(mailer.Dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("couldn't create mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405Z"), uuid.NewString())
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", msg.To, msg.Subject, msg.Body)
+
+	return os.WriteFile(filepath.Join(mailer.Dir, name), []byte(content), 0o600)
+}
+
+// Send emails through an SMTP server. STARTTLS is used whenever the server offers it.
+// Credentials are never sent over a plain connection to a remote server.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (mailer SMTPMailer) Send(ctx context.Context, msg Message) error {
+	host, _, err := net.SplitHostPort(mailer.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", mailer.Addr)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("couldn't start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return fmt.Errorf("couldn't start TLS: %w", err)
+		}
+	}
+	if mailer.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", mailer.Username, mailer.Password, host))
+		if err != nil {
+			return fmt.Errorf("couldn't authenticate to SMTP server: %w", err)
+		}
+	}
+
+	err = client.Mail(mailer.From)
+	if err != nil {
+		return fmt.Errorf("sender rejected: %w", err)
+	}
+	err = client.Rcpt(msg.To)
+	if err != nil {
+		return fmt.Errorf("recipient rejected: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(mailer.content(msg))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// Email with headers. Line breaks are removed from header values, so they can't add headers.
+func (mailer SMTPMailer) content(msg Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var content strings.Builder
+	fmt.Fprintf(&content, "From: %s\r\n", header.Replace(mailer.From))
+	fmt.Fprintf(&content, "To: %s\r\n", header.Replace(msg.To))
+	fmt.Fprintf(&content, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", header.Replace(msg.Subject)))
+	fmt.Fprintf(&content, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	content.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	// the data writer turns line breaks of the body into CRLF and escapes leading dots
+	content.WriteString(msg.Body)
+	content.WriteString("\n")
+
+	return []byte(content.String())
+}
+
+// Create mailer by its kind.
+// There is no default, since `KindLog` writes links with secret tokens into logs.
+func New(config Config) (mailer Mailer, err error) {
+	switch strings.ToLower(config.Kind) {
+	case "":
+		return mailer, errors.New("mailer kind is not set")
+	case KindLog:
+		return LogMailer{}, nil
+	case KindFile:
+		if config.Dir == "" {
+			return mailer, errors.New("mail directory is not set")
+		}
+		return FileMailer{Dir: config.Dir}, nil
+	case KindSMTP:
+		if config.SMTPAddr == "" || config.From == "" {
+			return mailer, errors.New("SMTP address and sender are required")
+		}
+		return SMTPMailer{
+			Addr:     config.SMTPAddr,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.From,
+		}, nil
+	default:
+		return mailer, fmt.Errorf("unknown mailer kind: %s", config.Kind)
+	}
+}
@@ -0,0 +1,157 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileMailerSend(t *testing.T) {
+	dir := t.TempDir()
+	mailer := FileMailer{Dir: dir}
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "Verify your email",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Send() wrote %d files, error = %v, want 1 file", len(files), err)
+	}
+	content, err := os.ReadFile(dir + "/" + files[0].Name())
+	if err != nil {
+		t.Fatalf("couldn't read mail file: %v", err)
+	}
+	for _, part := range []string{"To: user@example.com", "Subject: Hello", "Verify your email"} {
+		if !strings.Contains(string(content), part) {
+			t.Errorf("Send() content = %q, missing %q", content, part)
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// minimal SMTP server without extensions that records the received message
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost")
+
+		var recipient string
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); command {
+			case "RCPT":
+				recipient = line
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- recipient + "\n" + string(data)
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	mailer := SMTPMailer{Addr: listener.Addr().String(), From: "chirpy@example.com"}
+	err = mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Hello\r\nBcc: other@example.com",
+		Body:    "Verify your email",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	content := <-received
+	for _, part := range []string{"RCPT TO:<user@example.com>", "From: chirpy@example.com", "To: user@example.com", "Verify your email"} {
+		if !strings.Contains(content, part) {
+			t.Errorf("Send() content = %q, missing %q", content, part)
+		}
+	}
+	if strings.Contains(content, "\nBcc:") {
+		t.Errorf("Send() content = %q, header injected through subject", content)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectedErr bool
+	}{
+		{
+			name:        "Log mailer",
+			config:      Config{Kind: KindLog},
+			expectedErr: false,
+		},
+		{
+			name:        "No mailer kind",
+			config:      Config{},
+			expectedErr: true,
+		},
+		{
+			name:        "File mailer",
+			config:      Config{Kind: KindFile, Dir: "mail"},
+			expectedErr: false,
+		},
+		{
+			name:        "File mailer without directory",
+			config:      Config{Kind: KindFile},
+			expectedErr: true,
+		},
+		{
+			name:        "SMTP mailer",
+			config:      Config{Kind: KindSMTP, SMTPAddr: "smtp.example.com:587", From: "chirpy@example.com"},
+			expectedErr: false,
+		},
+		{
+			name:        "SMTP mailer without sender",
+			config:      Config{Kind: KindSMTP, SMTPAddr: "smtp.example.com:587"},
+			expectedErr: true,
+		},
+		{
+			name:        "Unknown mailer",
+			config:      Config{Kind: "sendmail"},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := New(testCase.config)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("New() error = %v, expectedErr %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
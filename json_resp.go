@@ -2,8 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+
+	"github.com/DIVIgor/chirpy/internal/auth"
 )
 
 // Form, log, and send request/response error
@@ -22,6 +26,52 @@ func respWithErr(writer http.ResponseWriter, statusCode int, msg string, err err
 	respJSON(writer, statusCode, errResp{Err: msg})
 }
 
+// Send 401 response with a WWW-Authenticate challenge (RFC 6750) describing why the access token was rejected
+func respWithTokenErr(writer http.ResponseWriter, err error) {
+	msg, code := "Couldn't validate JWT", "invalid_token"
+	switch {
+	case errors.Is(err, auth.ErrNoAuthHeader):
+		msg, code = "Couldn't find JWT", ""
+	case errors.Is(err, auth.ErrAuthHeaderFormat):
+		msg, code = "Couldn't find JWT", "invalid_request"
+	case errors.Is(err, auth.ErrTokenExpired):
+		msg = "Access token has expired"
+	case errors.Is(err, auth.ErrTokenNotValidYet):
+		msg = "Access token is not valid yet"
+	case errors.Is(err, auth.ErrTokenSignature):
+		msg = "Access token signature is invalid"
+	case errors.Is(err, auth.ErrTokenIssuer):
+		msg = "Access token issuer is invalid"
+	case errors.Is(err, auth.ErrTokenAudience):
+		msg = "Access token audience is invalid"
+	case errors.Is(err, auth.ErrTokenRevoked):
+		msg = "Access token has been revoked"
+	case errors.Is(err, auth.ErrTokenMalformed):
+		msg = "Access token is malformed"
+	}
+
+	// requests without credentials get no error code
+	challenge := `Bearer realm="chirpy"`
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q, error_description=%q`, code, msg)
+	}
+
+	writer.Header().Set("WWW-Authenticate", challenge)
+	respWithErr(writer, http.StatusUnauthorized, msg, err)
+}
+
+// Send 422 response listing every field that failed validation
+func respWithValidationErr(writer http.ResponseWriter, errs validationErrors) {
+	type errResp struct {
+		Err    string           `json:"error"`
+		Fields validationErrors `json:"fields"`
+	}
+	respJSON(writer, http.StatusUnprocessableEntity, errResp{
+		Err:    "Invalid input",
+		Fields: errs,
+	})
+}
+
 // Form and send JSON response
 func respJSON(writer http.ResponseWriter, statusCode int, payload interface{}) {
 	writer.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/auth"
+)
+
+// Load JWT keys from the environment.
+//
+// With `JWT_KEYS_DIR` tokens are signed with the key `JWT_ACTIVE_KID` from that directory,
+// and `SECRET`, if set, only verifies HS256 tokens issued before the switch.
+// Otherwise tokens are signed with `SECRET` using HS256.
+func loadJWTKeys() (*auth.KeySet, error) {
+	secret := os.Getenv("SECRET")
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if keysDir == "" {
+		if secret == "" {
+			return nil, errors.New("neither JWT_KEYS_DIR nor SECRET is set")
+		}
+		return auth.NewHMACKeySet(secret), nil
+	}
+
+	jwtKeys, err := auth.LoadKeySet(keysDir, os.Getenv("JWT_ACTIVE_KID"))
+	if err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		jwtKeys.AddLegacySecret(secret)
+	}
+
+	return jwtKeys, nil
+}
+
+// Read allowed clock skew for access token validation from `JWT_LEEWAY` (e.g. 30s)
+func loadJWTLeeway() (leeway time.Duration, err error) {
+	leewayStr := os.Getenv("JWT_LEEWAY")
+	if leewayStr == "" {
+		return 0, nil
+	}
+
+	leeway, err = time.ParseDuration(leewayStr)
+	if err != nil || leeway < 0 {
+		return 0, fmt.Errorf("invalid JWT_LEEWAY %q", leewayStr)
+	}
+
+	return leeway, nil
+}
+
+// Serve public keys used to sign access tokens
+func (cfg *apiConfig) handlerJWKS(writer http.ResponseWriter, req *http.Request) {
+	// keys change rarely, but retired keys should disappear from caches soon after rotation
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	respJSON(writer, http.StatusOK, cfg.jwtKeys.JWKS())
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Fill like counters of chirps and, if the viewer is known, whether they liked them
+func (cfg *apiConfig) addLikes(ctx context.Context, chirps []Chirp, viewerID uuid.NullUUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	counts, err := cfg.dbQueries.CountChirpLikes(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	likeCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		likeCounts[count.ChirpID] = count.LikeCount
+	}
+
+	likedByViewer := map[uuid.UUID]struct{}{}
+	if viewerID.Valid {
+		liked, err := cfg.dbQueries.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+			UserID:   viewerID.UUID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return err
+		}
+		for _, chirpID := range liked {
+			likedByViewer[chirpID] = struct{}{}
+		}
+	}
+
+	for idx := range chirps {
+		chirps[idx].LikeCount = likeCounts[chirps[idx].ID]
+		if viewerID.Valid {
+			_, liked := likedByViewer[chirps[idx].ID]
+			chirps[idx].LikedByMe = &liked
+		}
+	}
+
+	return nil
+}
+
+// Like or unlike chirp depending on `like`
+func (cfg *apiConfig) setChirpLike(writer http.ResponseWriter, req *http.Request, like bool) {
+	postID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	userID := requestUserID(req)
+
+	// likes of a rechirp count for its original chirp
+	chirp, err := cfg.getRechirpTarget(req.Context(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get chirp", err)
+		}
+		return
+	}
+
+	if like {
+		err = cfg.dbQueries.LikeChirp(req.Context(), database.LikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirp.ID,
+		})
+	} else {
+		err = cfg.dbQueries.UnlikeChirp(req.Context(), database.UnlikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirp.ID,
+		})
+	}
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update like", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// Like chirp on behalf of the authenticated user. Liking twice has no effect.
+func (cfg *apiConfig) handlerLikeChirp(writer http.ResponseWriter, req *http.Request) {
+	cfg.setChirpLike(writer, req, true)
+}
+
+// Remove like of the authenticated user from chirp
+func (cfg *apiConfig) handlerUnlikeChirp(writer http.ResponseWriter, req *http.Request) {
+	cfg.setChirpLike(writer, req, false)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+)
+
+// Delay after the first failed login. It doubles with every further failure until the lockout.
+const loginBackoffBase time.Duration = time.Second
+
+// How often stale failure counters are removed from DB
+const loginThrottleSweepInterval time.Duration = time.Hour
+
+// Failed login tracking per account and per client IP, stored in Postgres so it survives restarts
+type loginThrottle struct {
+	db                 *sql.DB
+	dbQueries          *database.Queries
+	maxAccountFailures int           // failures before an account is locked
+	maxIPFailures      int           // failures before a client IP is locked
+	lockout            time.Duration // lock time, also the time after which failures are forgotten
+	mu                 sync.Mutex
+	lastSweep          time.Time
+}
+
+// Read limits from LOGIN_MAX_FAILURES, LOGIN_MAX_IP_FAILURES, and LOGIN_LOCKOUT
+func loadLoginThrottle(db *sql.DB, dbQueries *database.Queries) (throttle *loginThrottle, err error) {
+	throttle = &loginThrottle{
+		db:                 db,
+		dbQueries:          dbQueries,
+		maxAccountFailures: 5,
+		maxIPFailures:      50,
+		lockout:            15 * time.Minute,
+		lastSweep:          time.Now(),
+	}
+
+	for envVar, limit := range map[string]*int{
+		"LOGIN_MAX_FAILURES":    &throttle.maxAccountFailures,
+		"LOGIN_MAX_IP_FAILURES": &throttle.maxIPFailures,
+	} {
+		limitStr := os.Getenv(envVar)
+		if limitStr == "" {
+			continue
+		}
+		*limit, err = strconv.Atoi(limitStr)
+		if err != nil || *limit < 1 {
+			return nil, fmt.Errorf("invalid %s %q", envVar, limitStr)
+		}
+	}
+
+	if lockoutStr := os.Getenv("LOGIN_LOCKOUT"); lockoutStr != "" {
+		throttle.lockout, err = time.ParseDuration(lockoutStr)
+		if err != nil || throttle.lockout <= 0 {
+			return nil, fmt.Errorf("invalid LOGIN_LOCKOUT %q", lockoutStr)
+		}
+	}
+
+	return throttle, nil
+}
+
+// Throttle subjects for a login attempt. Emails are tracked whether or not the account exists.
+func loginSubjects(email, ip string) (account, client string) {
+	return "email:" + strings.ToLower(email), "ip:" + ip
+}
+
+// Login attempt counted as failed until the password turns out to be correct
+type loginAttempt struct {
+	throttle        *loginThrottle
+	account         string
+	client          string
+	accountFailures int
+	clientFailures  int
+}
+
+// Count login attempt for the account and the client before checking the password.
+//
+// Both subjects are reserved in one transaction, so parallel attempts can't pass the limit.
+// If either subject is blocked, no attempt is returned along with the time left until the next try.
+func (throttle *loginThrottle) reserve(ctx context.Context, email, ip string) (attempt *loginAttempt, wait time.Duration, err error) {
+	throttle.sweep()
+
+	account, client := loginSubjects(email, ip)
+	attempt = &loginAttempt{throttle: throttle, account: account, client: client}
+
+	tx, err := throttle.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	qtx := throttle.dbQueries.WithTx(tx)
+
+	// subjects are always locked in the same order, so concurrent reservations can't deadlock
+	for _, reserved := range []struct {
+		subject  string
+		limit    int
+		failures *int
+	}{
+		{account, throttle.maxAccountFailures, &attempt.accountFailures},
+		{client, throttle.maxIPFailures, &attempt.clientFailures},
+	} {
+		row, err := qtx.ReserveLoginAttempt(ctx, database.ReserveLoginAttemptParams{
+			Subject:     reserved.subject,
+			ResetBefore: time.Now().UTC().Add(-throttle.lockout),
+			MaxFailures: int32(reserved.limit),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			wait, err = throttle.retryAfter(ctx, account, client)
+			return nil, wait, err
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		*reserved.failures = int(row.Failures)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attempt, 0, nil
+}
+
+// Time left until the account and the client may try to log in again.
+// Subjects at the limit whose last attempts are still being checked aren't blocked yet,
+// so the wait is never shorter than the first backoff.
+func (throttle *loginThrottle) retryAfter(ctx context.Context, account, client string) (wait time.Duration, err error) {
+	blocks, err := throttle.dbQueries.GetLoginBlocks(ctx, []string{account, client})
+	if err != nil {
+		return 0, err
+	}
+
+	wait = loginBackoffBase
+	for _, block := range blocks {
+		wait = max(wait, time.Until(block.BlockedUntil))
+	}
+
+	return wait, nil
+}
+
+// Block both subjects for a growing delay, or for the whole lockout once their limit is reached.
+// Recording isn't canceled along with the request, so dropping connections doesn't skip the backoff.
+func (attempt *loginAttempt) failed(ctx context.Context) error {
+	ctx = context.WithoutCancel(ctx)
+	throttle := attempt.throttle
+
+	for subject, delay := range map[string]time.Duration{
+		attempt.account: throttle.delay(attempt.accountFailures, throttle.maxAccountFailures),
+		attempt.client:  throttle.delay(attempt.clientFailures, throttle.maxIPFailures),
+	} {
+		err := throttle.dbQueries.BlockLogin(ctx, database.BlockLoginParams{
+			Subject:      subject,
+			BlockedUntil: time.Now().UTC().Add(delay),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Forget failures of the account and take the attempt back from the client.
+// Other client failures are kept, so logging into an own account doesn't reset guessing of others.
+func (attempt *loginAttempt) succeeded(ctx context.Context) error {
+	ctx = context.WithoutCancel(ctx)
+
+	err := attempt.throttle.dbQueries.ClearLoginFailures(ctx, attempt.account)
+	if err != nil {
+		return err
+	}
+
+	return attempt.throttle.dbQueries.ReleaseLoginAttempt(ctx, attempt.client)
+}
+
+// Delay after `failures` consecutive failures: 1s, 2s, 4s... capped by the lockout
+func (throttle *loginThrottle) delay(failures, limit int) time.Duration {
+	if failures >= limit {
+		return throttle.lockout
+	}
+
+	backoff := float64(loginBackoffBase) * math.Pow(2, float64(failures-1))
+	return time.Duration(min(backoff, float64(throttle.lockout)))
+}
+
+// Remove counters that are neither blocked nor recent enough to count, at most once per interval
+func (throttle *loginThrottle) sweep() {
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+
+	if time.Since(throttle.lastSweep) < loginThrottleSweepInterval {
+		return
+	}
+	throttle.lastSweep = time.Now()
+
+	go func() {
+		err := throttle.dbQueries.DeleteStaleLoginThrottles(context.Background(), time.Now().UTC().Add(-throttle.lockout))
+		if err != nil {
+			log.Println("Couldn't delete stale login throttles:", err)
+		}
+	}()
+}
+
+// Send 429 response telling when to retry (rounded up to whole seconds)
+func respWithTooManyAttempts(writer http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	writer.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respWithErr(writer, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+}
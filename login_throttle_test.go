@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleDelay(t *testing.T) {
+	throttle := &loginThrottle{lockout: 15 * time.Second}
+
+	tests := []struct {
+		name          string
+		failures      int
+		limit         int
+		expectedDelay time.Duration
+	}{
+		{name: "First failure", failures: 1, limit: 10, expectedDelay: time.Second},
+		{name: "Second failure", failures: 2, limit: 10, expectedDelay: 2 * time.Second},
+		{name: "Third failure", failures: 3, limit: 10, expectedDelay: 4 * time.Second},
+		{name: "Backoff capped by lockout", failures: 6, limit: 10, expectedDelay: 15 * time.Second},
+		{name: "Limit reached", failures: 3, limit: 3, expectedDelay: 15 * time.Second},
+		{name: "Limit passed", failures: 4, limit: 3, expectedDelay: 15 * time.Second},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if delay := throttle.delay(testCase.failures, testCase.limit); delay != testCase.expectedDelay {
+				t.Errorf("delay() = %v, expected %v", delay, testCase.expectedDelay)
+			}
+		})
+	}
+}
+
+func TestLoginSubjects(t *testing.T) {
+	tests := []struct {
+		name            string
+		email           string
+		ip              string
+		expectedAccount string
+		expectedClient  string
+	}{
+		{
+			name:            "Lowercase email",
+			email:           "user@example.com",
+			ip:              "203.0.113.5",
+			expectedAccount: "email:user@example.com",
+			expectedClient:  "ip:203.0.113.5",
+		},
+		{
+			name:            "Mixed case email",
+			email:           "User@Example.COM",
+			ip:              "203.0.113.5",
+			expectedAccount: "email:user@example.com",
+			expectedClient:  "ip:203.0.113.5",
+		},
+		{
+			name:            "IPv6 client",
+			email:           "user@example.com",
+			ip:              "2001:db8::1",
+			expectedAccount: "email:user@example.com",
+			expectedClient:  "ip:2001:db8::1",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			account, client := loginSubjects(testCase.email, testCase.ip)
+			if account != testCase.expectedAccount || client != testCase.expectedClient {
+				t.Errorf("loginSubjects() = %q, %q, expected %q, %q", account, client, testCase.expectedAccount, testCase.expectedClient)
+			}
+		})
+	}
+}
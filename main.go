@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"strings"
 	"sync/atomic"
 
+	"github.com/DIVIgor/chirpy/internal/auth"
 	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/DIVIgor/chirpy/internal/mailer"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -23,11 +26,18 @@ func handlerReadiness(writer http.ResponseWriter, req *http.Request) {
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db             *sql.DB
 	dbQueries      *database.Queries
+	mailer         mailer.Mailer
+	jwtKeys        *auth.KeySet
+	revocations    *revocationStore
+	loginThrottle  *loginThrottle
 	// .env params
-	platform  string // dev or prod
-	jwtSecret string
-	polkaKey  string
+	platform             string // dev or prod
+	polkaKey             string
+	baseURL              string         // used in links sent by email
+	trustedProxies       []netip.Prefix // proxies allowed to set X-Forwarded-For
+	requireVerifiedEmail bool           // block chirp creation until email is verified
 }
 
 // Count requests to the server (main paths only)
@@ -61,6 +71,71 @@ func adminPath(reqMethod, path string) (modifiedPath string) {
 	return pathMod(reqMethod, "/admin", path)
 }
 
+// Register all server paths. Files from `filePathRoot` are served under /app/.
+func (cfg *apiConfig) routes(filePathRoot string) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Main path
+	mux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filePathRoot)))))
+	// Secondary paths:
+	// • API:
+	// 	- server health
+	mux.HandleFunc(apiPath("GET", "/healthz"), handlerReadiness)
+	// 	- account
+	mux.HandleFunc(apiPath("POST", "/users"), cfg.handlerCreateUser)
+	mux.HandleFunc(apiPath("PUT", "/users"), cfg.middlewareAuth(cfg.handlerUpdateUser))
+	mux.HandleFunc(apiPath("PATCH", "/users"), cfg.middlewareAuth(cfg.handlerPatchUser))
+	mux.HandleFunc(apiPath("DELETE", "/users"), cfg.middlewareAuth(cfg.handlerDeleteUser))
+	mux.HandleFunc(apiPath("GET", "/users/me/export"), cfg.middlewareAuth(cfg.handlerExportUser))
+	mux.HandleFunc(apiPath("GET", "/users/verify"), cfg.handlerVerifyEmail)
+	mux.HandleFunc(apiPath("POST", "/users/verify/resend"), cfg.middlewareAuth(cfg.handlerResendVerification))
+	mux.HandleFunc(apiPath("POST", "/login"), cfg.handlerLogin)
+	mux.HandleFunc(apiPath("POST", "/refresh"), cfg.handlerRefreshAccess)
+	mux.HandleFunc(apiPath("POST", "/revoke"), cfg.handlerRevokeAccess)
+	mux.HandleFunc(apiPath("GET", "/sessions"), cfg.middlewareAuth(cfg.handlerGetSessions))
+	mux.HandleFunc(apiPath("DELETE", "/sessions/{sessionID}"), cfg.middlewareAuth(cfg.handlerDeleteSession))
+	mux.HandleFunc(apiPath("POST", "/sessions/revoke-all"), cfg.middlewareAuth(cfg.handlerRevokeAllSessions))
+	mux.HandleFunc(apiPath("POST", "/password/forgot"), cfg.handlerForgotPassword)
+	mux.HandleFunc(apiPath("POST", "/password/reset"), cfg.handlerResetPassword)
+	mux.HandleFunc(apiPath("GET", "/users/me/mentions"), cfg.middlewareAuth(cfg.handlerGetMentions))
+	// 	- profiles (by user ID or handle)
+	mux.HandleFunc(apiPath("GET", "/users/{user}"), cfg.handlerGetProfile)
+	// 	- follows
+	mux.HandleFunc(apiPath("POST", "/users/{userID}/follow"), cfg.middlewareAuth(cfg.handlerFollowUser))
+	mux.HandleFunc(apiPath("DELETE", "/users/{userID}/follow"), cfg.middlewareAuth(cfg.handlerUnfollowUser))
+	mux.HandleFunc(apiPath("GET", "/users/{userID}/followers"), cfg.handlerGetFollowers)
+	mux.HandleFunc(apiPath("GET", "/users/{userID}/following"), cfg.handlerGetFollowing)
+	mux.HandleFunc(apiPath("GET", "/timeline"), cfg.middlewareAuth(cfg.handlerGetTimeline))
+	// 	- posts
+	mux.HandleFunc(apiPath("POST", "/chirps"), cfg.middlewareAuth(cfg.handlerCreateChirp))
+	mux.HandleFunc(apiPath("GET", "/chirps"), cfg.middlewareOptionalAuth(cfg.handlerGetChirpList))
+	mux.HandleFunc(apiPath("GET", "/chirps/{chirpID}"), cfg.middlewareOptionalAuth(cfg.handlerGetChirp))
+	mux.HandleFunc(apiPath("PUT", "/chirps/{chirpID}"), cfg.middlewareAuth(cfg.handlerUpdateChirp))
+	mux.HandleFunc(apiPath("DELETE", "/chirps/{chirpID}"), cfg.middlewareAuth(cfg.handlerDeleteChirp))
+	mux.HandleFunc(apiPath("GET", "/chirps/{chirpID}/revisions"), cfg.handlerGetChirpRevisions)
+	mux.HandleFunc(apiPath("GET", "/chirps/{chirpID}/thread"), cfg.middlewareOptionalAuth(cfg.handlerGetChirpThread))
+	mux.HandleFunc(apiPath("POST", "/chirps/{chirpID}/likes"), cfg.middlewareAuth(cfg.handlerLikeChirp))
+	mux.HandleFunc(apiPath("DELETE", "/chirps/{chirpID}/likes"), cfg.middlewareAuth(cfg.handlerUnlikeChirp))
+	mux.HandleFunc(apiPath("POST", "/chirps/{chirpID}/rechirps"), cfg.middlewareAuth(cfg.handlerCreateRechirp))
+	mux.HandleFunc(apiPath("DELETE", "/chirps/{chirpID}/rechirps"), cfg.middlewareAuth(cfg.handlerDeleteRechirp))
+	// 	- search
+	mux.HandleFunc(apiPath("GET", "/search/chirps"), cfg.middlewareOptionalAuth(cfg.handlerSearchChirps))
+	// 	- hashtags (trending ones live outside /tags, so they can't be mistaken for a tag)
+	mux.HandleFunc(apiPath("GET", "/trending/tags"), cfg.handlerGetTrendingTags)
+	mux.HandleFunc(apiPath("GET", "/tags/{tag}"), cfg.middlewareOptionalAuth(cfg.handlerGetTagChirps))
+	// 	- webhooks
+	mux.HandleFunc(apiPath("POST", "/polka/webhooks"), cfg.handlerUpgradeUserPlan)
+	// • Public keys for verification of access tokens by other services
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handlerJWKS)
+	// • Administration:
+	// 	- metrics
+	mux.HandleFunc(adminPath("GET", "/metrics"), cfg.handlerCountVisits)
+	// 	- reset DB
+	mux.HandleFunc(adminPath("POST", "/reset"), cfg.handlerResetVisits)
+
+	return mux
+}
+
 func main() {
 	const filePathRoot string = "."
 	const port string = "8080"
@@ -73,51 +148,68 @@ func main() {
 		log.Fatal("Cannot connect to database:", err)
 	}
 
-	jwtSecret := os.Getenv("SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT secret is not set.")
+	jwtKeys, err := loadJWTKeys()
+	if err != nil {
+		log.Fatal("Cannot load JWT keys:", err)
+	}
+	jwtLeeway, err := loadJWTLeeway()
+	if err != nil {
+		log.Fatal("Cannot set JWT leeway:", err)
+	}
+	jwtKeys.SetLeeway(jwtLeeway)
+	dbQueries := database.New(db)
+	revocations := newRevocationStore(dbQueries)
+	jwtKeys.SetRevocationStore(revocations)
+	loginThrottle, err := loadLoginThrottle(db, dbQueries)
+	if err != nil {
+		log.Fatal("Cannot set login limits:", err)
 	}
 	polkaKey := os.Getenv("POLKA_KEY")
 	if polkaKey == "" {
 		log.Fatal("Polka key is not set.")
 	}
 
-	apiCfg := &apiConfig{
-		dbQueries: database.New(db),
-		platform:  os.Getenv("PLATFORM"),
-		jwtSecret: jwtSecret,
-		polkaKey:  polkaKey,
+	// log, file, or smtp; logging emails is only fine in development, since they contain secret links
+	mailerKind := os.Getenv("MAILER")
+	if mailerKind == "" && os.Getenv("PLATFORM") == "dev" {
+		mailerKind = mailer.KindLog
+	}
+	appMailer, err := mailer.New(mailer.Config{
+		Kind:         mailerKind,
+		Dir:          os.Getenv("MAIL_DIR"),
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		From:         os.Getenv("MAIL_FROM"),
+	})
+	if err != nil {
+		log.Fatal("Cannot set up mailer:", err)
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal("Cannot set trusted proxies:", err)
 	}
-	mux := http.NewServeMux()
-
-	// Main path
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filePathRoot)))))
-	// Secondary paths:
-	// • API:
-	// 	- server health
-	mux.HandleFunc(apiPath("GET", "/healthz"), handlerReadiness)
-	// 	- account
-	mux.HandleFunc(apiPath("POST", "/users"), apiCfg.handlerCreateUser)
-	mux.HandleFunc(apiPath("PUT", "/users"), apiCfg.handlerUpdateUser)
-	mux.HandleFunc(apiPath("POST", "/login"), apiCfg.handlerLogin)
-	mux.HandleFunc(apiPath("POST", "/refresh"), apiCfg.handlerRefreshAccess)
-	mux.HandleFunc(apiPath("POST", "/revoke"), apiCfg.handlerRevokeAccess)
-	// 	- posts
-	mux.HandleFunc(apiPath("POST", "/chirps"), apiCfg.handlerCreateChirp)
-	mux.HandleFunc(apiPath("GET", "/chirps"), apiCfg.handlerGetChirpList)
-	mux.HandleFunc(apiPath("GET", "/chirps/{chirpID}"), apiCfg.handlerGetChirp)
-	mux.HandleFunc(apiPath("DELETE", "/chirps/{chirpID}"), apiCfg.handlerDeleteChirp)
-	// 	- webhooks
-	mux.HandleFunc(apiPath("POST", "/polka/webhooks"), apiCfg.handlerUpgradeUserPlan)
-	// • Administration:
-	// 	- metrics
-	mux.HandleFunc(adminPath("GET", "/metrics"), apiCfg.handlerCountVisits)
-	// 	- reset DB
-	mux.HandleFunc(adminPath("POST", "/reset"), apiCfg.handlerResetVisits)
 
+	apiCfg := &apiConfig{
+		db:                   db,
+		dbQueries:            dbQueries,
+		mailer:               appMailer,
+		platform:             os.Getenv("PLATFORM"),
+		jwtKeys:              jwtKeys,
+		revocations:          revocations,
+		loginThrottle:        loginThrottle,
+		polkaKey:             polkaKey,
+		baseURL:              strings.TrimSuffix(baseURL, "/"),
+		trustedProxies:       trustedProxies,
+		requireVerifiedEmail: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+	}
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: apiCfg.routes(filePathRoot),
 	}
 
 	// Simple info
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	mux := (&apiConfig{}).routes(".")
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedPattern string
+	}{
+		{
+			name:            "Trending tags",
+			method:          http.MethodGet,
+			path:            "/api/trending/tags",
+			expectedPattern: "GET /api/trending/tags",
+		},
+		{
+			name:            "Tag named trending",
+			method:          http.MethodGet,
+			path:            "/api/tags/trending",
+			expectedPattern: "GET /api/tags/{tag}",
+		},
+		{
+			name:            "Own mentions",
+			method:          http.MethodGet,
+			path:            "/api/users/me/mentions",
+			expectedPattern: "GET /api/users/me/mentions",
+		},
+		{
+			name:            "Profile by handle",
+			method:          http.MethodGet,
+			path:            "/api/users/chirper",
+			expectedPattern: "GET /api/users/{user}",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, testCase.path, nil)
+			if _, pattern := mux.Handler(req); pattern != testCase.expectedPattern {
+				t.Errorf("route %s %s = %q, expected %q", testCase.method, testCase.path, pattern, testCase.expectedPattern)
+			}
+		})
+	}
+}
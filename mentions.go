@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// "@" followed by a handle which isn't glued to a preceding word (like in emails)
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]{3,30})\b`)
+
+// Extract unique lowercase handles (without "@") in order of appearance
+func extractMentions(text string) (handles []string) {
+	seen := map[string]struct{}{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(match[1])
+		if _, exists := seen[handle]; exists {
+			continue
+		}
+		seen[handle] = struct{}{}
+		handles = append(handles, handle)
+	}
+
+	return handles
+}
+
+// Replace mentions of a chirp with users whose handles are found in its body.
+//
+// Unknown handles and the author mentioning themselves are ignored.
+func saveChirpMentions(ctx context.Context, queries *database.Queries, chirp database.ChirpRecord) error {
+	err := queries.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	handles := extractMentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+
+	users, err := queries.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+	userIDs := []uuid.UUID{}
+	for _, user := range users {
+		if user.ID != chirp.UserID {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	return queries.SaveChirpMentions(ctx, database.SaveChirpMentionsParams{
+		ChirpID:   chirp.ID,
+		UserIds:   userIDs,
+		CreatedAt: chirp.CreatedAt,
+	})
+}
+
+// Get a page of chirps mentioning the authenticated user, newest first
+func (cfg *apiConfig) handlerGetMentions(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	limit, cursor, err := parsePageParams(req.URL.Query(), newestFirst)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListMentionChirpsParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if cursor != nil {
+		params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	chirps, err := cfg.dbQueries.ListMentionChirps(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve mentions", err)
+		return
+	}
+
+	page := newChirpPage(chirps, limit, newestFirst)
+	err = cfg.prepareChirps(req.Context(), page.Chirps, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, page)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name            string
+		text            string
+		expectedHandles []string
+	}{
+		{name: "No mentions", text: "Just chirping", expectedHandles: nil},
+		{name: "Start of text", text: "@alice hi", expectedHandles: []string{"alice"}},
+		{name: "Lowercased and unique", text: "@Alice @alice @ALICE", expectedHandles: []string{"alice"}},
+		{name: "Order of appearance", text: "@bob, @alice and @bob", expectedHandles: []string{"bob", "alice"}},
+		{name: "Punctuation around", text: "(@alice) @bob's", expectedHandles: []string{"alice", "bob"}},
+		{name: "Email address", text: "write to a@b.com or user@example.com", expectedHandles: nil},
+		{name: "Dotted prefix", text: "see .@alice", expectedHandles: nil},
+		{name: "Double at", text: "@@alice", expectedHandles: nil},
+		{name: "Too short", text: "@al", expectedHandles: nil},
+		{name: "Longest handle", text: "@" + strings.Repeat("a", 30), expectedHandles: []string{strings.Repeat("a", 30)}},
+		// a longer word isn't cut to the first 30 characters
+		{name: "Too long", text: "@" + strings.Repeat("a", 31), expectedHandles: nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			handles := extractMentions(testCase.text)
+			if !slices.Equal(handles, testCase.expectedHandles) {
+				t.Errorf("extractMentions(%q) = %q, expected %q", testCase.text, handles, testCase.expectedHandles)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Number of items per page if the client didn't ask for a specific amount
+const defaultPageLimit int32 = 20
+
+// Upper bound for the `limit` query parameter
+const maxPageLimit int32 = 100
+
+// Sort keys accepted by the `sort_by` query parameter
+const (
+	sortByCreatedAt string = "created_at"
+	sortByUpdatedAt string = "updated_at"
+	sortByRelevance string = "relevance"
+)
+
+// Position of the last item on a page used for keyset pagination
+//
+// Encoded into an opaque string, so clients should pass it back as is.
+// `SortBy` and `Desc` pin the cursor to the ordering it was made for.
+// `Key` replaces `Time` for orderings by a numeric value (e.g. search rank).
+type pageCursor struct {
+	Time   time.Time `json:"time"`
+	Key    float64   `json:"key,omitempty"`
+	ID     uuid.UUID `json:"id"`
+	SortBy string    `json:"sort_by"`
+	Desc   bool      `json:"desc"`
+}
+
+// Requested ordering of a list
+type sortParams struct {
+	SortBy string
+	Desc   bool
+}
+
+// Encode cursor to an URL-safe string
+func encodeCursor(cursor pageCursor) (encoded string) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode cursor previously made by `encodeCursor`
+func decodeCursor(encoded string) (cursor pageCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.ID == uuid.Nil {
+		return cursor, errors.New("invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+// Read `sort` (asc or desc) and `sort_by` query parameters.
+//
+// The first of `sortKeys` is used if `sort_by` is missing.
+func parseSortParams(query url.Values, sortKeys ...string) (sorting sortParams, err error) {
+	switch query.Get("sort") {
+	case "", "asc":
+	case "desc":
+		sorting.Desc = true
+	default:
+		return sorting, errors.New("sort must be either asc or desc")
+	}
+
+	sorting.SortBy = sortKeys[0]
+	if sortBy := query.Get("sort_by"); sortBy != "" {
+		if !slices.Contains(sortKeys, sortBy) {
+			return sorting, errors.New("sort_by must be one of: " + strings.Join(sortKeys, ", "))
+		}
+		sorting.SortBy = sortBy
+	}
+
+	return sorting, nil
+}
+
+// Read `limit` and `cursor` query parameters.
+//
+// Missing cursor means the first page. The cursor must be made for the same `sorting`.
+func parsePageParams(query url.Values, sorting sortParams) (limit int32, cursor *pageCursor, err error) {
+	limit = defaultPageLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || parsed < 1 || int32(parsed) > maxPageLimit {
+			return limit, cursor, errors.New("limit must be between 1 and " + strconv.Itoa(int(maxPageLimit)))
+		}
+		limit = int32(parsed)
+	}
+
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		decoded, err := decodeCursor(cursorStr)
+		if err != nil {
+			return limit, cursor, err
+		}
+		if decoded.SortBy != sorting.SortBy || decoded.Desc != sorting.Desc {
+			return limit, cursor, errors.New("cursor doesn't match requested sorting")
+		}
+		cursor = &decoded
+	}
+
+	return limit, cursor, nil
+}
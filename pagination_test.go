@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	cursor := pageCursor{
+		Time:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ID:     uuid.New(),
+		SortBy: sortByCreatedAt,
+		Desc:   true,
+	}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if decoded != cursor {
+		t.Errorf("decodeCursor() = %+v, expected %+v", decoded, cursor)
+	}
+
+	for name, encoded := range map[string]string{
+		"Not base64":    "not a cursor!",
+		"Not JSON":      base64.RawURLEncoding.EncodeToString([]byte("cursor")),
+		"Missing ID":    base64.RawURLEncoding.EncodeToString([]byte(`{"time":"2024-05-01T12:00:00Z"}`)),
+		"Empty string":  "",
+		"Wrong ID type": base64.RawURLEncoding.EncodeToString([]byte(`{"id":42}`)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decodeCursor(encoded); err == nil {
+				t.Errorf("decodeCursor(%q) expected error", encoded)
+			}
+		})
+	}
+}
+
+func TestParseSortParams(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           url.Values
+		expectedSorting sortParams
+		expectedErr     bool
+	}{
+		{
+			name:            "Defaults",
+			query:           url.Values{},
+			expectedSorting: sortParams{SortBy: sortByCreatedAt},
+		},
+		{
+			name:            "Descending by another key",
+			query:           url.Values{"sort": {"desc"}, "sort_by": {sortByUpdatedAt}},
+			expectedSorting: sortParams{SortBy: sortByUpdatedAt, Desc: true},
+		},
+		{
+			name:        "Invalid direction",
+			query:       url.Values{"sort": {"down"}},
+			expectedErr: true,
+		},
+		{
+			name:        "Key not allowed for the list",
+			query:       url.Values{"sort_by": {"likes"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			sorting, err := parseSortParams(testCase.query, sortByCreatedAt, sortByUpdatedAt)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("parseSortParams() error = %v, expectedErr %v", err, testCase.expectedErr)
+				return
+			}
+			if !testCase.expectedErr && sorting != testCase.expectedSorting {
+				t.Errorf("parseSortParams() = %+v, expected %+v", sorting, testCase.expectedSorting)
+			}
+		})
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	sorting := sortParams{SortBy: sortByCreatedAt, Desc: true}
+	cursorID := uuid.New()
+	matchingCursor := encodeCursor(pageCursor{ID: cursorID, SortBy: sortByCreatedAt, Desc: true})
+	otherKeyCursor := encodeCursor(pageCursor{ID: cursorID, SortBy: sortByUpdatedAt, Desc: true})
+	otherDirectionCursor := encodeCursor(pageCursor{ID: cursorID, SortBy: sortByCreatedAt})
+
+	tests := []struct {
+		name          string
+		query         url.Values
+		expectedLimit int32
+		expectCursor  bool
+		expectedErr   bool
+	}{
+		{name: "Defaults", query: url.Values{}, expectedLimit: defaultPageLimit},
+		{name: "Maximum limit", query: url.Values{"limit": {"100"}}, expectedLimit: maxPageLimit},
+		{name: "Limit too high", query: url.Values{"limit": {"101"}}, expectedErr: true},
+		{name: "Zero limit", query: url.Values{"limit": {"0"}}, expectedErr: true},
+		{name: "Limit out of int32", query: url.Values{"limit": {"4294967297"}}, expectedErr: true},
+		{name: "Limit not a number", query: url.Values{"limit": {"ten"}}, expectedErr: true},
+		{
+			name:          "Matching cursor",
+			query:         url.Values{"cursor": {matchingCursor}, "limit": {"5"}},
+			expectedLimit: 5,
+			expectCursor:  true,
+		},
+		{name: "Cursor for another key", query: url.Values{"cursor": {otherKeyCursor}}, expectedErr: true},
+		{name: "Cursor for another direction", query: url.Values{"cursor": {otherDirectionCursor}}, expectedErr: true},
+		{name: "Invalid cursor", query: url.Values{"cursor": {"garbage"}}, expectedErr: true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			limit, cursor, err := parsePageParams(testCase.query, sorting)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("parsePageParams() error = %v, expectedErr %v", err, testCase.expectedErr)
+				return
+			}
+			if testCase.expectedErr {
+				return
+			}
+			if limit != testCase.expectedLimit {
+				t.Errorf("parsePageParams() limit = %d, expected %d", limit, testCase.expectedLimit)
+			}
+			if (cursor != nil) != testCase.expectCursor {
+				t.Errorf("parsePageParams() cursor = %+v, expectCursor %v", cursor, testCase.expectCursor)
+			}
+			if cursor != nil && cursor.ID != cursorID {
+				t.Errorf("parsePageParams() cursor ID = %v, expected %v", cursor.ID, cursorID)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/auth"
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/DIVIgor/chirpy/internal/mailer"
+)
+
+// Reset tokens are valid for 1 hour
+const passwordResetTTL time.Duration = time.Hour
+
+// Send a password reset token to the account email.
+//
+// Always responds with 202, so the endpoint can't be used to find out registered emails.
+func (cfg *apiConfig) handlerForgotPassword(writer http.ResponseWriter, req *http.Request) {
+	type forgotRequest struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	data := forgotRequest{}
+	err := decoder.Decode(&data)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUser(req.Context(), strings.TrimSpace(data.Email))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Couldn't get user for password reset: %s", err)
+		}
+		writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't create reset token", err)
+		return
+	}
+
+	_, err = cfg.dbQueries.SavePasswordResetToken(req.Context(), database.SavePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save reset token", err)
+		return
+	}
+
+	err = cfg.mailer.Send(req.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Send the token below with a new password to %s/api/password/reset:\n\n%s\n\n"+
+			"The token expires in %s. If you didn't ask for a password reset, ignore this email.",
+			cfg.baseURL, token, passwordResetTTL),
+	})
+	if err != nil {
+		log.Printf("Couldn't send password reset email to user %s: %s", user.ID, err)
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// Set a new password with a reset token and log out of every session
+func (cfg *apiConfig) handlerResetPassword(writer http.ResponseWriter, req *http.Request) {
+	type resetRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	data := resetRequest{}
+	err := decoder.Decode(&data)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if data.Token == "" {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't find token", nil)
+		return
+	}
+	errs := validationErrors{}
+	errs.check("password", validatePassword(data.Password))
+	if len(errs) > 0 {
+		respWithValidationErr(writer, errs)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(data.Password)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't hash password", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	resetToken, err := qtx.UsePasswordResetToken(req.Context(), auth.HashToken(data.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusBadRequest, "Invalid or expired token", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't reset password", err)
+		}
+		return
+	}
+
+	err = qtx.SetUserPassword(req.Context(), database.SetUserPasswordParams{
+		ID:             resetToken.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+
+	// other reset links and existing sessions shouldn't outlive the old password
+	err = qtx.ExpirePasswordResetTokens(req.Context(), resetToken.UserID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	err = qtx.RevokeUserTokens(req.Context(), resetToken.UserID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+
+	err = cfg.revocations.RevokeUser(req.Context(), resetToken.UserID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Public user data. Never includes email or other private fields.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Get public profile of a user by ID or handle from URL
+func (cfg *apiConfig) handlerGetProfile(writer http.ResponseWriter, req *http.Request) {
+	userRef := req.PathValue("user")
+
+	var user database.User
+	var err error
+	if userID, parseErr := uuid.Parse(userRef); parseErr == nil {
+		user, err = cfg.dbQueries.GetUserByID(req.Context(), userID)
+	} else {
+		handle, handleErr := validateHandle(userRef)
+		if handleErr != nil {
+			respWithErr(writer, http.StatusNotFound, "User not found", handleErr)
+			return
+		}
+		user, err = cfg.dbQueries.GetUserByHandle(req.Context(), sql.NullString{String: handle, Valid: true})
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "User not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get user", err)
+		}
+		return
+	}
+
+	respJSON(writer, http.StatusOK, Profile{
+		ID:          user.ID,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		IsChirpyRed: user.IsChirpyRed,
+		CreatedAt:   user.CreatedAt,
+	})
+}
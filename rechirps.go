@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Original chirp embedded into a rechirp
+type RechirpOrigin struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Fill original chirp data of rechirps
+func (cfg *apiConfig) addRechirpOrigins(ctx context.Context, chirps []Chirp) error {
+	originIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			originIDs = append(originIDs, chirp.RechirpOf.ChirpID)
+		}
+	}
+	if len(originIDs) == 0 {
+		return nil
+	}
+
+	origins, err := cfg.dbQueries.GetChirpsByIDs(ctx, originIDs)
+	if err != nil {
+		return err
+	}
+	originsByID := make(map[uuid.UUID]database.ChirpRecord, len(origins))
+	for _, origin := range origins {
+		originsByID[origin.ID] = origin
+	}
+
+	for _, chirp := range chirps {
+		if chirp.RechirpOf == nil {
+			continue
+		}
+		origin, exists := originsByID[chirp.RechirpOf.ChirpID]
+		if !exists {
+			continue
+		}
+		chirp.RechirpOf.UserID = origin.UserID
+		chirp.RechirpOf.Body = origin.Body
+		chirp.RechirpOf.CreatedAt = origin.CreatedAt
+		chirp.RechirpOf.UpdatedAt = origin.UpdatedAt
+	}
+
+	return nil
+}
+
+// Find the chirp to rechirp or like by ID from URL. Rechirps are resolved to their original chirp.
+func (cfg *apiConfig) getRechirpTarget(ctx context.Context, postID uuid.UUID) (database.ChirpRecord, error) {
+	target, err := cfg.dbQueries.GetChirp(ctx, postID)
+	if err != nil {
+		return target, err
+	}
+	if target.RechirpOf.Valid {
+		return cfg.dbQueries.GetChirp(ctx, target.RechirpOf.UUID)
+	}
+
+	return target, nil
+}
+
+// Rechirp someone else's chirp on behalf of the authenticated user
+func (cfg *apiConfig) handlerCreateRechirp(writer http.ResponseWriter, req *http.Request) {
+	postID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	userID := requestUserID(req)
+
+	allowed, err := cfg.canPost(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+	if !allowed {
+		respWithErr(writer, http.StatusForbidden, "Email is not verified", nil)
+		return
+	}
+
+	origin, err := cfg.getRechirpTarget(req.Context(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get chirp", err)
+		}
+		return
+	}
+	if origin.UserID == userID {
+		respWithErr(writer, http.StatusForbidden, "You can't rechirp your own chirp", nil)
+		return
+	}
+
+	rechirp, err := cfg.dbQueries.CreateRechirp(req.Context(), database.CreateRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: origin.ID, Valid: true},
+	})
+	if err != nil {
+		// nothing is returned if the user has already rechirped it
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusConflict, "Chirp is already rechirped", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't save rechirp to DB", err)
+		}
+		return
+	}
+
+	chirpList := []Chirp{chirpFromDB(database.ChirpRecord(rechirp))}
+	err = cfg.prepareChirps(req.Context(), chirpList, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusCreated, chirpList[0])
+}
+
+// Undo rechirp of the authenticated user
+func (cfg *apiConfig) handlerDeleteRechirp(writer http.ResponseWriter, req *http.Request) {
+	postID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	userID := requestUserID(req)
+
+	origin, err := cfg.getRechirpTarget(req.Context(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get chirp", err)
+		}
+		return
+	}
+
+	_, err = cfg.dbQueries.DeleteRechirp(req.Context(), database.DeleteRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: origin.ID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "Rechirp not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't delete rechirp", err)
+		}
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -1,6 +1,30 @@
 package main
 
-import "errors"
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password length limits. bcrypt ignores everything past 72 bytes.
+const (
+	minPasswordLength int = 8
+	maxPasswordBytes  int = 72
+)
+
+// Handles are used in mentions, so they are limited to characters matched by `mentionPattern`
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// Handles that collide with /api/users/... routes or could pass for the service itself
+var reservedHandles = map[string]bool{
+	"verify": true,
+	"admin":  true,
+	"api":    true,
+	"chirpy": true,
+}
 
 func validateChirp(message string) (string, error) {
 	// limit messages to 140 symbols
@@ -16,3 +40,100 @@ func validateChirp(message string) (string, error) {
 
 	return message, nil
 }
+
+// Check handle format and return it in lowercase
+func validateHandle(handle string) (string, error) {
+	handle = strings.ToLower(strings.TrimPrefix(handle, "@"))
+	if !handlePattern.MatchString(handle) {
+		return "", errors.New("Handle must be 3-30 letters, digits, or underscores")
+	}
+	if reservedHandles[handle] {
+		return "", errors.New("Handle is reserved")
+	}
+
+	return handle, nil
+}
+
+// Limit display names to 50 symbols
+func validateDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > 50 {
+		return "", errors.New("Display name is too long")
+	}
+
+	return name, nil
+}
+
+// Limit bio to 160 symbols
+func validateBio(bio string) (string, error) {
+	bio = strings.TrimSpace(bio)
+	if utf8.RuneCountInString(bio) > 160 {
+		return "", errors.New("Bio is too long")
+	}
+
+	return bio, nil
+}
+
+// Validation failure of a single request field
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Every failing field of a request, so clients can show all problems at once
+type validationErrors []fieldError
+
+// Record `err` for `field` if it isn't nil
+func (errs *validationErrors) check(field string, err error) {
+	if err != nil {
+		*errs = append(*errs, fieldError{Field: field, Message: err.Error()})
+	}
+}
+
+func (errs validationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Check email syntax (RFC 5322 address without a display name) and return it in lowercase
+func validateEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", errors.New("Email is required")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", errors.New("Email is invalid")
+	}
+
+	return strings.ToLower(email), nil
+}
+
+// Require passwords of 8 to 72 bytes with at least one letter and one digit
+func validatePassword(password string) error {
+	if password == "" {
+		return errors.New("Password is required")
+	}
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return errors.New("Password must be at least 8 characters long")
+	}
+	if len(password) > maxPasswordBytes {
+		return errors.New("Password must be at most 72 bytes long")
+	}
+
+	hasLetter, hasDigit := false, false
+	for _, char := range password {
+		hasLetter = hasLetter || unicode.IsLetter(char)
+		hasDigit = hasDigit || unicode.IsDigit(char)
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("Password must contain a letter and a digit")
+	}
+
+	return nil
+}
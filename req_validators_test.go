@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		expectedEmail string
+		expectedErr   bool
+	}{
+		{
+			name:          "Valid email",
+			email:         "user@example.com",
+			expectedEmail: "user@example.com",
+		},
+		{
+			name:          "Mixed case with spaces",
+			email:         "  User@Example.COM ",
+			expectedEmail: "user@example.com",
+		},
+		{
+			name:        "Empty email",
+			email:       "   ",
+			expectedErr: true,
+		},
+		{
+			name:        "Missing domain",
+			email:       "user@",
+			expectedErr: true,
+		},
+		{
+			name:        "Display name",
+			email:       "User <user@example.com>",
+			expectedErr: true,
+		},
+		{
+			name:        "Several addresses",
+			email:       "user@example.com, other@example.com",
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			email, err := validateEmail(testCase.email)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("validateEmail() error = %v, expectedErr %v", err, testCase.expectedErr)
+				return
+			}
+			if email != testCase.expectedEmail {
+				t.Errorf("validateEmail() email = %q, expected %q", email, testCase.expectedEmail)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		expectedErr bool
+	}{
+		{name: "Valid password", password: "password1"},
+		{name: "Empty password", password: "", expectedErr: true},
+		{name: "Too short", password: "pass123", expectedErr: true},
+		{name: "No digit", password: "password", expectedErr: true},
+		{name: "No letter", password: "12345678", expectedErr: true},
+		{name: "Non-ASCII letters", password: "пароль12"},
+		// bcrypt ignores everything past 72 bytes, so longer passwords are rejected
+		{name: "72 bytes", password: "a1" + strings.Repeat("a", 70)},
+		{name: "73 bytes", password: "a1" + strings.Repeat("a", 71), expectedErr: true},
+		{name: "72 bytes of multibyte characters", password: "1a" + strings.Repeat("ж", 35)},
+		{name: "37 multibyte characters over 72 bytes", password: "1" + strings.Repeat("ж", 36), expectedErr: true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validatePassword(testCase.password)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("validatePassword() error = %v, expectedErr %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateHandle(t *testing.T) {
+	tests := []struct {
+		name           string
+		handle         string
+		expectedHandle string
+		expectedErr    bool
+	}{
+		{name: "Valid handle", handle: "chirper_1", expectedHandle: "chirper_1"},
+		{name: "Leading @ and upper case", handle: "@Chirper", expectedHandle: "chirper"},
+		{name: "Too short", handle: "ab", expectedErr: true},
+		{name: "Too long", handle: strings.Repeat("a", 31), expectedErr: true},
+		{name: "Invalid characters", handle: "chirp.er", expectedErr: true},
+		{name: "Reserved route", handle: "verify", expectedErr: true},
+		{name: "Reserved in upper case", handle: "@Admin", expectedErr: true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			handle, err := validateHandle(testCase.handle)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("validateHandle() error = %v, expectedErr %v", err, testCase.expectedErr)
+				return
+			}
+			if handle != testCase.expectedHandle {
+				t.Errorf("validateHandle() handle = %q, expected %q", handle, testCase.expectedHandle)
+			}
+		})
+	}
+}
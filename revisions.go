@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Previous body of an edited chirp
+type ChirpRevision struct {
+	ID      uuid.UUID `json:"id"`
+	ChirpID uuid.UUID `json:"chirp_id"`
+	Body    string    `json:"body"`
+	// when this body was posted or set by an earlier edit
+	WrittenAt time.Time `json:"written_at"`
+	// when this body was replaced by an edit
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// Get edit history of a chirp from the oldest body to the latest replaced one
+func (cfg *apiConfig) handlerGetChirpRevisions(writer http.ResponseWriter, req *http.Request) {
+	postID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	_, err = cfg.dbQueries.GetChirp(req.Context(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusNotFound, "Chirp not found", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't get chirp", err)
+		}
+		return
+	}
+
+	revisions, err := cfg.dbQueries.GetChirpRevisions(req.Context(), postID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp revisions", err)
+		return
+	}
+
+	revisionList := []ChirpRevision{}
+	for _, revision := range revisions {
+		revisionList = append(revisionList, ChirpRevision{
+			ID:         revision.ID,
+			ChirpID:    revision.ChirpID,
+			Body:       revision.Body,
+			WrittenAt:  revision.WrittenAt,
+			ReplacedAt: revision.CreatedAt,
+		})
+	}
+
+	respJSON(writer, http.StatusOK, revisionList)
+}
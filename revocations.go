@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// How long a "not revoked" answer from DB is trusted.
+// Revocations made by other server instances are picked up within this time.
+const revocationCacheTTL time.Duration = 30 * time.Second
+
+// How often expired entries are removed from the cache and DB
+const revocationSweepInterval time.Duration = 10 * time.Minute
+
+// Access token revocations stored in Postgres with an in-memory cache in front
+type revocationStore struct {
+	dbQueries  *database.Queries
+	mu         sync.Mutex
+	revoked    map[uuid.UUID]time.Time // token ID -> token expiration
+	notRevoked map[uuid.UUID]time.Time // token ID -> end of caching
+	lastSweep  time.Time
+}
+
+func newRevocationStore(dbQueries *database.Queries) *revocationStore {
+	return &revocationStore{
+		dbQueries:  dbQueries,
+		revoked:    map[uuid.UUID]time.Time{},
+		notRevoked: map[uuid.UUID]time.Time{},
+		lastSweep:  time.Now(),
+	}
+}
+
+// Check if access token has been revoked
+func (store *revocationStore) IsRevoked(ctx context.Context, tokenID uuid.UUID) (revoked bool, err error) {
+	now := time.Now()
+	store.mu.Lock()
+	store.sweep(now)
+	_, revoked = store.revoked[tokenID]
+	cachedUntil, checked := store.notRevoked[tokenID]
+	store.mu.Unlock()
+	if revoked || (checked && now.Before(cachedUntil)) {
+		return revoked, nil
+	}
+
+	revoked, err = store.dbQueries.IsAccessTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if revoked {
+		// the exact expiration isn't needed, the entry only has to outlive the token
+		store.revoked[tokenID] = now.Add(accessTokenTTL)
+	} else {
+		store.notRevoked[tokenID] = now.Add(revocationCacheTTL)
+	}
+
+	return revoked, nil
+}
+
+// Revoke a single access token valid until `expiresAt`
+func (store *revocationStore) Revoke(ctx context.Context, tokenID, userID uuid.UUID, expiresAt time.Time) error {
+	err := store.dbQueries.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		TokenID:   tokenID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	store.cacheRevoked(tokenID, expiresAt)
+	return nil
+}
+
+// Revoke access token recorded on a refresh token, unless it is missing or has already expired
+func (store *revocationStore) RevokeIssued(ctx context.Context, tokenID uuid.NullUUID, userID uuid.UUID, expiresAt sql.NullTime) error {
+	if !tokenID.Valid || !expiresAt.Time.After(time.Now().UTC()) {
+		return nil
+	}
+
+	return store.Revoke(ctx, tokenID.UUID, userID, expiresAt.Time)
+}
+
+// Revoke every unexpired access token issued to the user
+func (store *revocationStore) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	tokens, err := store.dbQueries.RevokeUserAccessTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		store.cacheRevoked(token.TokenID, token.ExpiresAt)
+	}
+	return nil
+}
+
+func (store *revocationStore) cacheRevoked(tokenID uuid.UUID, expiresAt time.Time) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.revoked[tokenID] = expiresAt
+	delete(store.notRevoked, tokenID)
+}
+
+// Drop expired cache entries and DB rows once in a while. Must be called with `mu` locked.
+func (store *revocationStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < revocationSweepInterval {
+		return
+	}
+	store.lastSweep = now
+
+	for tokenID, expiresAt := range store.revoked {
+		if now.After(expiresAt) {
+			delete(store.revoked, tokenID)
+		}
+	}
+	for tokenID, cachedUntil := range store.notRevoked {
+		if now.After(cachedUntil) {
+			delete(store.notRevoked, tokenID)
+		}
+	}
+
+	go func() {
+		err := store.dbQueries.DeleteExpiredAccessTokenRevocations(context.Background())
+		if err != nil {
+			log.Println("Couldn't delete expired access token revocations:", err)
+		}
+	}()
+}
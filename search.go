@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Search chirps by words from `q` using web search syntax ("quoted phrases", or, -excluded).
+//
+// Results are the most relevant first unless sorted by creation or update date
+// and can be filtered by author the same way as the chirp list.
+func (cfg *apiConfig) handlerSearchChirps(writer http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	searchQuery := strings.TrimSpace(query.Get("q"))
+	if searchQuery == "" {
+		respWithErr(writer, http.StatusBadRequest, "Search query is empty", errors.New("missing q parameter"))
+		return
+	}
+
+	sorting, err := parseSortParams(query, sortByRelevance, sortByCreatedAt, sortByUpdatedAt)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	// the most relevant results go first unless asked otherwise
+	if sorting.SortBy == sortByRelevance && query.Get("sort") == "" {
+		sorting.Desc = true
+	}
+
+	params := database.SearchChirpsParams{
+		Query:    searchQuery,
+		SortBy:   sorting.SortBy,
+		SortDesc: sorting.Desc,
+	}
+
+	params.AuthorID, err = parseAuthorFilter(query)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't parse user id", err)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(query, sorting)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	// fetch one extra row to find out if there is a next page
+	params.PageLimit = limit + 1
+	if cursor != nil {
+		params.CursorKey = sql.NullFloat64{Float64: cursor.Key, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	results, err := cfg.dbQueries.SearchChirps(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't search chirps", err)
+		return
+	}
+
+	page := chirpPage{Chirps: []Chirp{}}
+	if len(results) > int(limit) {
+		results = results[:limit]
+		last := results[len(results)-1]
+		page.NextCursor = encodeCursor(pageCursor{
+			Key:    last.SortKey,
+			ID:     last.ChirpRecord.ID,
+			SortBy: sorting.SortBy,
+			Desc:   sorting.Desc,
+		})
+	}
+	for _, result := range results {
+		page.Chirps = append(page.Chirps, chirpFromDB(result.ChirpRecord))
+	}
+
+	err = cfg.prepareChirps(req.Context(), page.Chirps, optionalUserID(req))
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, page)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Login session of a user. A session outlives refresh token rotations, so its ID is the token family.
+type Session struct {
+	ID          uuid.UUID `json:"id"`
+	UserAgent   string    `json:"user_agent"`
+	IPAddress   string    `json:"ip_address"`
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Parse comma-separated IP addresses and CIDR ranges of proxies in front of the server
+func parseTrustedProxies(proxiesStr string) (proxies []netip.Prefix, err error) {
+	for _, proxy := range strings.Split(proxiesStr, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// Get client IP address of the request.
+//
+// X-Forwarded-For is read only from trusted proxies, since it can be set by anyone.
+// Addresses are taken from the right, skipping trusted proxies, so clients can't spoof them by prepending.
+func (cfg *apiConfig) clientIP(req *http.Request) string {
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		ip = host
+	}
+
+	forwarded := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0 && cfg.isTrustedProxy(ip); i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		ip = hop
+	}
+
+	return ip
+}
+
+func (cfg *apiConfig) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, proxy := range cfg.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// List active sessions of the authenticated user, newest logins first
+func (cfg *apiConfig) handlerGetSessions(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	dbSessions, err := cfg.dbQueries.ListSessions(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve sessions", err)
+		return
+	}
+
+	sessions := []Session{}
+	for _, session := range dbSessions {
+		sessions = append(sessions, Session{
+			ID:          session.FamilyID,
+			UserAgent:   session.UserAgent,
+			IPAddress:   session.IpAddress,
+			CreatedAt:   session.CreatedAt,
+			RefreshedAt: session.RefreshedAt,
+			ExpiresAt:   session.ExpiresAt,
+		})
+	}
+
+	respJSON(writer, http.StatusOK, sessions)
+}
+
+// Revoke a single session of the authenticated user along with its access tokens
+func (cfg *apiConfig) handlerDeleteSession(writer http.ResponseWriter, req *http.Request) {
+	sessionID, err := uuid.Parse(req.PathValue("sessionID"))
+	if err != nil {
+		respWithErr(writer, http.StatusNotFound, "Session not found", err)
+		return
+	}
+
+	userID := requestUserID(req)
+
+	// sessions of other users are reported as missing
+	revoked, err := cfg.dbQueries.RevokeSession(req.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+	if len(revoked) == 0 {
+		respWithErr(writer, http.StatusNotFound, "Session not found", nil)
+		return
+	}
+
+	for _, token := range revoked {
+		err = cfg.revocations.RevokeIssued(req.Context(), token.AccessTokenID, userID, token.AccessExpiresAt)
+		if err != nil {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+			return
+		}
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// Revoke every session and access token of the authenticated user
+func (cfg *apiConfig) handlerRevokeAllSessions(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	err := cfg.dbQueries.RevokeUserTokens(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	err = cfg.revocations.RevokeUser(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
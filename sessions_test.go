@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &apiConfig{trustedProxies: trustedProxies}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{
+			name:       "Direct client",
+			remoteAddr: "203.0.113.5:4321",
+			expectedIP: "203.0.113.5",
+		},
+		{
+			name:         "Forwarding header from untrusted peer",
+			remoteAddr:   "203.0.113.5:4321",
+			forwardedFor: []string{"198.51.100.7"},
+			expectedIP:   "203.0.113.5",
+		},
+		{
+			name:         "Trusted proxy",
+			remoteAddr:   "10.1.2.3:4321",
+			forwardedFor: []string{"198.51.100.7"},
+			expectedIP:   "198.51.100.7",
+		},
+		{
+			name:         "Chain of trusted proxies",
+			remoteAddr:   "10.1.2.3:4321",
+			forwardedFor: []string{"198.51.100.7, 192.168.1.1"},
+			expectedIP:   "198.51.100.7",
+		},
+		{
+			name:         "Spoofed address prepended by client",
+			remoteAddr:   "10.1.2.3:4321",
+			forwardedFor: []string{"1.2.3.4, 198.51.100.7"},
+			expectedIP:   "198.51.100.7",
+		},
+		{
+			name:         "Several header lines",
+			remoteAddr:   "10.1.2.3:4321",
+			forwardedFor: []string{"1.2.3.4", "198.51.100.7"},
+			expectedIP:   "198.51.100.7",
+		},
+		{
+			name:         "Invalid forwarded address",
+			remoteAddr:   "10.1.2.3:4321",
+			forwardedFor: []string{"unknown"},
+			expectedIP:   "10.1.2.3",
+		},
+		{
+			name:       "Trusted proxy without header",
+			remoteAddr: "10.1.2.3:4321",
+			expectedIP: "10.1.2.3",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := &http.Request{RemoteAddr: testCase.remoteAddr, Header: http.Header{}}
+			for _, value := range testCase.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if ip := cfg.clientIP(req); ip != testCase.expectedIP {
+				t.Errorf("clientIP() = %v, expected %v", ip, testCase.expectedIP)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name        string
+		proxies     string
+		expectedLen int
+		expectedErr bool
+	}{
+		{name: "Empty", proxies: "", expectedLen: 0},
+		{name: "Addresses and ranges", proxies: "10.0.0.0/8, ::1,172.16.0.1", expectedLen: 3},
+		{name: "Invalid address", proxies: "10.0.0.0/8, proxy.local", expectedErr: true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			proxies, err := parseTrustedProxies(testCase.proxies)
+			if (err != nil) != testCase.expectedErr {
+				t.Errorf("parseTrustedProxies() error = %v, expectedErr %v", err, testCase.expectedErr)
+				return
+			}
+			if len(proxies) != testCase.expectedLen {
+				t.Errorf("parseTrustedProxies() returned %d proxies, expected %d", len(proxies), testCase.expectedLen)
+			}
+		})
+	}
+}
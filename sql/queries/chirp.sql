@@ -1,26 +1,114 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(id, body, user_id, created_at, updated_at)
-VALUES (gen_random_uuid(), $1, $2, NOW(), NOW())
-RETURNING *;
+INSERT INTO chirps(id, body, user_id, parent_id, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
+RETURNING id, body, user_id, created_at, updated_at, parent_id, rechirp_of;
 
--- name: GetChirps :many
-SELECT *
-FROM chirps
-ORDER BY created_at;
+-- name: CreateRechirp :one
+INSERT INTO chirps(id, body, user_id, rechirp_of, created_at, updated_at)
+VALUES (gen_random_uuid(), '', $1, $2, NOW(), NOW())
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, body, user_id, created_at, updated_at, parent_id, rechirp_of;
 
--- name: GetUserChirps :many
+-- name: ListChirps :many
 SELECT *
-FROM chirps
-WHERE user_id = $1
-ORDER BY created_at;
+FROM chirp_records
+WHERE (sqlc.narg('author_id')::UUID IS NULL OR user_id = sqlc.narg('author_id'))
+    AND (sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (NOT sqlc.arg('sort_desc')::BOOLEAN
+            AND (CASE WHEN sqlc.arg('sort_by')::TEXT = 'updated_at' THEN updated_at ELSE created_at END, id)
+                > (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID))
+        OR (sqlc.arg('sort_desc')::BOOLEAN
+            AND (CASE WHEN sqlc.arg('sort_by')::TEXT = 'updated_at' THEN updated_at ELSE created_at END, id)
+                < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID)))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_desc')::BOOLEAN AND sqlc.arg('sort_by')::TEXT = 'updated_at' THEN updated_at END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::BOOLEAN AND sqlc.arg('sort_by')::TEXT <> 'updated_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::BOOLEAN THEN id END DESC,
+    CASE WHEN NOT sqlc.arg('sort_desc')::BOOLEAN AND sqlc.arg('sort_by')::TEXT = 'updated_at' THEN updated_at END,
+    CASE WHEN NOT sqlc.arg('sort_desc')::BOOLEAN AND sqlc.arg('sort_by')::TEXT <> 'updated_at' THEN created_at END,
+    id
+LIMIT sqlc.arg('page_limit');
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirp_records), matches.sort_key
+FROM chirp_records
+JOIN (
+    SELECT id,
+        (CASE sqlc.arg('sort_by')::TEXT
+            WHEN 'created_at' THEN EXTRACT(EPOCH FROM created_at)
+            WHEN 'updated_at' THEN EXTRACT(EPOCH FROM updated_at)
+            ELSE ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query')::TEXT))
+        END)::DOUBLE PRECISION AS sort_key
+    FROM chirps
+    WHERE search_vector @@ websearch_to_tsquery('english', sqlc.arg('query')::TEXT)
+        AND (sqlc.narg('author_id')::UUID IS NULL OR user_id = sqlc.narg('author_id'))
+) AS matches ON chirp_records.id = matches.id
+WHERE sqlc.narg('cursor_key')::DOUBLE PRECISION IS NULL
+    OR (NOT sqlc.arg('sort_desc')::BOOLEAN
+        AND (matches.sort_key, chirp_records.id) > (sqlc.narg('cursor_key'), sqlc.narg('cursor_id')::UUID))
+    OR (sqlc.arg('sort_desc')::BOOLEAN
+        AND (matches.sort_key, chirp_records.id) < (sqlc.narg('cursor_key'), sqlc.narg('cursor_id')::UUID))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_desc')::BOOLEAN THEN matches.sort_key END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::BOOLEAN THEN chirp_records.id END DESC,
+    matches.sort_key,
+    chirp_records.id
+LIMIT sqlc.arg('page_limit');
 
 -- name: GetChirp :one
 SELECT *
-FROM chirps
+FROM chirp_records
 WHERE ID = $1;
 
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirp_records
+WHERE id = ANY(sqlc.arg('ids')::UUID[]);
+
+-- name: GetChirpThread :many
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.id, chirps.parent_id
+    FROM chirps
+    WHERE chirps.id = $1
+    UNION ALL
+    SELECT chirps.id, chirps.parent_id
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.parent_id
+), thread AS (
+    SELECT ancestors.id
+    FROM ancestors
+    WHERE ancestors.parent_id IS NULL
+    UNION ALL
+    SELECT chirps.id
+    FROM chirps
+    JOIN thread ON chirps.parent_id = thread.id
+)
+SELECT chirp_records.*
+FROM chirp_records
+WHERE chirp_records.id IN (SELECT thread.id FROM thread)
+ORDER BY chirp_records.created_at, chirp_records.id;
+
+-- name: GetChirpForUpdate :one
+SELECT *
+FROM chirp_records
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $3,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, body, user_id, created_at, updated_at, parent_id, rechirp_of;
+
 -- name: DeleteChirp :one
 DELETE
 FROM chirps
 WHERE id = $1 AND user_id = $2
-RETURNING *;
\ No newline at end of file
+RETURNING id;
+
+-- name: DeleteRechirp :one
+DELETE
+FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2
+RETURNING id;
\ No newline at end of file
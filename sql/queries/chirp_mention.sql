@@ -0,0 +1,19 @@
+-- name: SaveChirpMentions :exec
+INSERT INTO chirp_mentions(chirp_id, user_id, created_at)
+SELECT sqlc.arg('chirp_id')::UUID, unnest(sqlc.arg('user_ids')::UUID[]), sqlc.arg('created_at')::TIMESTAMP
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE
+FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: ListMentionChirps :many
+SELECT chirp_records.*
+FROM chirp_records
+JOIN chirp_mentions ON chirp_records.id = chirp_mentions.chirp_id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (chirp_records.created_at, chirp_records.id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID))
+ORDER BY chirp_records.created_at DESC, chirp_records.id DESC
+LIMIT sqlc.arg('page_limit');
\ No newline at end of file
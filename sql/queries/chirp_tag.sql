@@ -0,0 +1,27 @@
+-- name: SaveChirpTags :exec
+INSERT INTO chirp_tags(chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id')::UUID, unnest(sqlc.arg('tags')::TEXT[]), sqlc.arg('created_at')::TIMESTAMP
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: DeleteChirpTags :exec
+DELETE
+FROM chirp_tags
+WHERE chirp_id = $1;
+
+-- name: ListTagChirps :many
+SELECT chirp_records.*
+FROM chirp_records
+JOIN chirp_tags ON chirp_records.id = chirp_tags.chirp_id
+WHERE chirp_tags.tag = sqlc.arg('tag')
+    AND (sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (chirp_records.created_at, chirp_records.id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID))
+ORDER BY chirp_records.created_at DESC, chirp_records.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetTrendingTags :many
+SELECT tag, COUNT(*) AS chirp_count
+FROM chirp_tags
+WHERE created_at > sqlc.arg('since')
+GROUP BY tag
+ORDER BY chirp_count DESC, tag
+LIMIT sqlc.arg('tag_limit');
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- name: SaveEmailVerificationToken :one
+INSERT INTO email_verification_tokens(token_hash, user_id, email, expires_at, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- name: FollowUser :exec
+INSERT INTO follows(follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE
+FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT *
+FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (created_at, follower_id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListFollowing :many
+SELECT *
+FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (created_at, followee_id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetTimeline :many
+SELECT chirp_records.*
+FROM chirp_records
+JOIN follows ON chirp_records.user_id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+    AND (sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (chirp_records.created_at, chirp_records.id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id')::UUID))
+ORDER BY chirp_records.created_at DESC, chirp_records.id DESC
+LIMIT sqlc.arg('page_limit');
\ No newline at end of file
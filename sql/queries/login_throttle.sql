@@ -0,0 +1,40 @@
+-- name: ReserveLoginAttempt :one
+-- counts the attempt before the password is checked, no row is returned
+-- for blocked subjects or subjects at the failure limit
+INSERT INTO login_throttles(subject, failures, blocked_until, updated_at)
+VALUES (sqlc.arg('subject'), 1, NOW(), NOW())
+ON CONFLICT (subject) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.updated_at < sqlc.arg('reset_before') THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    updated_at = NOW()
+WHERE login_throttles.blocked_until <= NOW()
+    AND (login_throttles.failures < sqlc.arg('max_failures')
+        OR login_throttles.updated_at < sqlc.arg('reset_before'))
+RETURNING *;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0)
+WHERE subject = $1;
+
+-- name: BlockLogin :exec
+UPDATE login_throttles
+SET blocked_until = GREATEST(blocked_until, $2)
+WHERE subject = $1;
+
+-- name: GetLoginBlocks :many
+SELECT *
+FROM login_throttles
+WHERE subject = ANY(sqlc.arg('subjects')::TEXT[])
+    AND blocked_until > NOW();
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_throttles
+WHERE subject = $1;
+
+-- name: DeleteStaleLoginThrottles :exec
+DELETE FROM login_throttles
+WHERE updated_at < $1
+    AND blocked_until < NOW();
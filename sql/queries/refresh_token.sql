@@ -1,19 +1,76 @@
 -- name: SaveRefreshToken :one
-INSERT INTO refresh_tokens(token, user_id, expires_at, created_at, updated_at)
-VALUES ($1, $2, $3, NOW(), NOW())
+INSERT INTO refresh_tokens(token_hash, user_id, family_id, parent_token_hash, user_agent, ip_address, access_token_id, access_expires_at, expires_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 RETURNING *;
 
--- name: GetUserFromToken :one
-SELECT users.*
-FROM users
-JOIN refresh_tokens
-ON users.id = refresh_tokens.user_id
-WHERE refresh_tokens.token = $1
+-- name: GetRefreshToken :one
+SELECT *
+FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    rotated_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
     AND revoked_at IS NULL
-    AND expires_at > NOW();
+    AND expires_at > NOW()
+RETURNING *;
 
--- name: RevokeToken :exec
+-- name: RevokeToken :one
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: RevokeTokenFamily :many
+-- tokens revoked earlier are included while their access token is still valid
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, NOW()),
+    updated_at = NOW()
+WHERE family_id = $1
+    AND (revoked_at IS NULL OR access_expires_at > NOW())
+RETURNING user_id, access_token_id, access_expires_at;
+
+-- name: RevokeSession :many
+-- tokens revoked earlier are included while their access token is still valid
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, NOW()),
+    updated_at = NOW()
+WHERE family_id = $1
+    AND user_id = $2
+    AND (revoked_at IS NULL OR access_expires_at > NOW())
+RETURNING access_token_id, access_expires_at;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+    AND revoked_at IS NULL;
+
+-- name: ListActiveRefreshTokens :many
+SELECT *
+FROM refresh_tokens
+WHERE user_id = $1
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+ORDER BY created_at;
+
+-- name: ListSessions :many
+SELECT refresh_tokens.family_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip_address,
+    logins.created_at,
+    refresh_tokens.created_at AS refreshed_at,
+    refresh_tokens.expires_at
+FROM refresh_tokens
+JOIN refresh_tokens AS logins
+ON logins.family_id = refresh_tokens.family_id
+    AND logins.parent_token_hash IS NULL
+WHERE refresh_tokens.user_id = $1
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+ORDER BY logins.created_at DESC;
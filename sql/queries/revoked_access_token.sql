@@ -0,0 +1,25 @@
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens(token_id, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (token_id) DO NOTHING;
+
+-- name: RevokeUserAccessTokens :many
+INSERT INTO revoked_access_tokens(token_id, user_id, expires_at, created_at)
+SELECT access_token_id, user_id, access_expires_at, NOW()
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+    AND access_token_id IS NOT NULL
+    AND access_expires_at > NOW()
+ON CONFLICT (token_id) DO NOTHING
+RETURNING token_id, expires_at;
+
+-- name: IsAccessTokenRevoked :one
+SELECT EXISTS(
+    SELECT 1
+    FROM revoked_access_tokens
+    WHERE token_id = $1
+);
+
+-- name: DeleteExpiredAccessTokenRevocations :exec
+DELETE FROM revoked_access_tokens
+WHERE expires_at < NOW();
@@ -1,26 +1,68 @@
 -- name: CreateUser :one
-INSERT INTO users(id, email, hashed_password, created_at, updated_at)
-VALUES (gen_random_uuid(), $1, $2, NOW(), NOW())
+INSERT INTO users(id, email, hashed_password, handle, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
 RETURNING *;
 
 -- name: GetUser :one
 SELECT *
 FROM users
-WHERE email = $1;
+WHERE lower(email) = lower(sqlc.arg('email'));
+
+-- name: GetUserByID :one
+SELECT *
+FROM users
+WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT *
+FROM users
+WHERE handle = $1;
+
+-- name: GetUsersByHandles :many
+SELECT *
+FROM users
+WHERE handle = ANY(sqlc.arg('handles')::TEXT[]);
 
 -- name: UpdateUser :one
 UPDATE users
-SET email = $2,
-    hashed_password = $3,
+SET email = sqlc.arg('email'),
+    email_verified_at = CASE WHEN email = sqlc.arg('email') THEN email_verified_at END,
+    hashed_password = sqlc.arg('hashed_password'),
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
     updated_at = NOW()
-WHERE id = $1
+WHERE id = sqlc.arg('id')
 RETURNING *;
 
+-- name: PatchUser :one
+UPDATE users
+SET email = COALESCE(sqlc.narg('email'), email),
+    email_verified_at = CASE WHEN email = COALESCE(sqlc.narg('email'), email) THEN email_verified_at END,
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: UpgradeUserPlan :one
 UPDATE users
 SET is_chirpy_red = TRUE
 WHERE id = $1
 RETURNING *;
 
+-- name: DeleteUser :exec
+DELETE
+FROM users
+WHERE id = $1;
+
 -- name: ClearUsers :exec
 DELETE FROM users;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX chirps_updated_at_id_idx ON chirps(updated_at, id);
+CREATE INDEX chirps_user_id_updated_at_id_idx ON chirps(user_id, updated_at, id);
+
+-- +goose Down
+DROP INDEX chirps_user_id_updated_at_id_idx;
+DROP INDEX chirps_updated_at_id_idx;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN parent_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_parent_id_idx ON chirps(parent_id);
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN parent_id;
\ No newline at end of file
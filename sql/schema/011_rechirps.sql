@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID REFERENCES chirps(id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps(user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL;
+
+-- +goose Down
+-- rechirps have no body of their own, so they can't be kept as plain chirps
+DELETE FROM chirps WHERE rechirp_of IS NOT NULL;
+
+ALTER TABLE chirps
+DROP COLUMN rechirp_of;
\ No newline at end of file
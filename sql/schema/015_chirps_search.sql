@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN search_vector TSVECTOR
+GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_vector_idx ON chirps USING GIN (search_vector);
+
+-- chirp columns without the search vector, which is only needed for matching.
+-- Queries read chirps from here, so new chirp columns have to be added to the view too.
+CREATE VIEW chirp_records AS
+SELECT id, body, user_id, created_at, updated_at, parent_id, rechirp_of
+FROM chirps;
+
+-- +goose Down
+DROP VIEW chirp_records;
+
+ALTER TABLE chirps
+DROP COLUMN search_vector;
\ No newline at end of file
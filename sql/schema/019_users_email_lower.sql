@@ -0,0 +1,41 @@
+-- +goose Up
+-- emails are stored in lowercase from now on, older ones are matched case-insensitively.
+-- Accounts whose emails differ only by case can't be merged automatically, so the migration
+-- fails and lists them. Change or delete all but one account of each email, then run it again.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    conflicts TEXT;
+BEGIN
+    SELECT string_agg(duplicate.accounts, E'\n' ORDER BY duplicate.email)
+    INTO conflicts
+    FROM (
+        SELECT lower(email) AS email,
+            lower(email) || ': ' || string_agg(id || ' (' || email || ')', ', ' ORDER BY created_at) AS accounts
+        FROM users
+        GROUP BY lower(email)
+        HAVING COUNT(*) > 1
+    ) AS duplicate;
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'emails differing only by case belong to several accounts'
+            USING DETAIL = conflicts,
+                HINT = 'Change or delete all but one account of each email listed in the detail.';
+    END IF;
+END $$;
+-- +goose StatementEnd
+
+UPDATE users
+SET email = lower(email),
+    updated_at = NOW()
+WHERE email <> lower(email);
+
+-- verification compares pending tokens with the stored email
+UPDATE email_verification_tokens
+SET email = lower(email)
+WHERE email <> lower(email);
+
+CREATE UNIQUE INDEX users_email_lower_key ON users(lower(email));
+
+-- +goose Down
+DROP INDEX users_email_lower_key;
\ No newline at end of file
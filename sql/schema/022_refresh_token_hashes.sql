@@ -0,0 +1,38 @@
+-- +goose Up
+-- only SHA-256 digests of refresh tokens are stored, existing tokens are converted in place
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT refresh_tokens_parent_token_fkey;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN parent_token TO parent_token_hash;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex'),
+    parent_token_hash = encode(sha256(convert_to(parent_token_hash, 'UTF8')), 'hex');
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT refresh_tokens_parent_token_hash_fkey
+FOREIGN KEY (parent_token_hash) REFERENCES refresh_tokens(token_hash) ON DELETE SET NULL;
+
+-- +goose Down
+-- digests can't be turned back into tokens, so every session is logged out
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT refresh_tokens_parent_token_hash_fkey;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN parent_token_hash TO parent_token;
+
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE revoked_at IS NULL;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT refresh_tokens_parent_token_fkey
+FOREIGN KEY (parent_token) REFERENCES refresh_tokens(token) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +goose Up
+-- no foreign key on user_id: revocations of deleted users must stay until their tokens expire
+CREATE TABLE revoked_access_tokens(
+    token_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens(expires_at);
+
+-- last access token issued for a refresh token, so it can be revoked along with the session
+ALTER TABLE refresh_tokens
+ADD COLUMN access_token_id UUID,
+ADD COLUMN access_expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN access_expires_at,
+DROP COLUMN access_token_id;
+
+DROP TABLE revoked_access_tokens;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+-- failed logins per account (email:<email>) and per client (ip:<address>)
+CREATE TABLE login_throttles(
+    subject TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    blocked_until TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE login_throttles;
\ No newline at end of file
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Trending tags are ranked over the last day unless the client asks for another window
+const defaultTrendingWindow time.Duration = 24 * time.Hour
+
+// Longest window for trending tags
+const maxTrendingWindow time.Duration = 7 * 24 * time.Hour
+
+// Number of trending tags returned if the client didn't ask for a specific amount
+const defaultTrendingLimit int32 = 10
+
+// Hashtag with the number of chirps using it within a time window
+type TrendingTag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+// Replace hashtags of a chirp with ones found in its body
+func saveChirpTags(ctx context.Context, queries *database.Queries, chirp database.ChirpRecord) error {
+	err := queries.DeleteChirpTags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	tags := extractHashtags(chirp.Body)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return queries.SaveChirpTags(ctx, database.SaveChirpTagsParams{
+		ChirpID:   chirp.ID,
+		Tags:      tags,
+		CreatedAt: chirp.CreatedAt,
+	})
+}
+
+// Get a page of chirps with hashtag from URL, newest first
+func (cfg *apiConfig) handlerGetTagChirps(writer http.ResponseWriter, req *http.Request) {
+	tag := normalizeHashtag(req.PathValue("tag"))
+	if tag == "" {
+		respWithErr(writer, http.StatusBadRequest, "Invalid tag", nil)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(req.URL.Query(), newestFirst)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListTagChirpsParams{
+		Tag:       tag,
+		PageLimit: limit + 1,
+	}
+	if cursor != nil {
+		params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	chirps, err := cfg.dbQueries.ListTagChirps(req.Context(), params)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	page := newChirpPage(chirps, limit, newestFirst)
+	err = cfg.prepareChirps(req.Context(), page.Chirps, optionalUserID(req))
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, page)
+}
+
+// Get hashtags used by the most chirps within `window` (e.g. "24h") before now
+func (cfg *apiConfig) handlerGetTrendingTags(writer http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	window := defaultTrendingWindow
+	if windowStr := query.Get("window"); windowStr != "" {
+		parsed, err := time.ParseDuration(windowStr)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			respWithErr(writer, http.StatusBadRequest, "window must be a duration up to "+maxTrendingWindow.String(), errors.New("invalid window"))
+			return
+		}
+		window = parsed
+	}
+
+	limit := defaultTrendingLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || parsed < 1 || int32(parsed) > maxPageLimit {
+			respWithErr(writer, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(int(maxPageLimit)), errors.New("invalid limit"))
+			return
+		}
+		limit = int32(parsed)
+	}
+
+	tags, err := cfg.dbQueries.GetTrendingTags(req.Context(), database.GetTrendingTagsParams{
+		Since:    time.Now().UTC().Add(-window),
+		TagLimit: limit,
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve trending tags", err)
+		return
+	}
+
+	trending := []TrendingTag{}
+	for _, tag := range tags {
+		trending = append(trending, TrendingTag{
+			Tag:        tag.Tag,
+			ChirpCount: tag.ChirpCount,
+		})
+	}
+
+	respJSON(writer, http.StatusOK, trending)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Chirp with its replies nested in chronological order
+type ChirpThread struct {
+	Chirp
+	Replies []*ChirpThread `json:"replies"`
+}
+
+// Build conversation tree from thread chirps sorted by creation date
+func buildThread(chirps []Chirp) (root *ChirpThread) {
+	nodes := make(map[uuid.UUID]*ChirpThread, len(chirps))
+	for _, chirp := range chirps {
+		nodes[chirp.ID] = &ChirpThread{
+			Chirp:   chirp,
+			Replies: []*ChirpThread{},
+		}
+	}
+
+	for _, chirp := range chirps {
+		node := nodes[chirp.ID]
+		if chirp.ParentID == nil {
+			root = node
+			continue
+		}
+		parent, exists := nodes[*chirp.ParentID]
+		if !exists {
+			root = node
+			continue
+		}
+		parent.Replies = append(parent.Replies, node)
+	}
+
+	return root
+}
+
+// Get the whole conversation a chirp belongs to, starting from its root chirp
+func (cfg *apiConfig) handlerGetChirpThread(writer http.ResponseWriter, req *http.Request) {
+	postID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpThread(req.Context(), postID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve thread", err)
+		return
+	}
+	if len(chirps) == 0 {
+		respWithErr(writer, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
+	chirpList := parseChirps(chirps)
+	err = cfg.prepareChirps(req.Context(), chirpList, optionalUserID(req))
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't retrieve chirp details", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, buildThread(chirpList))
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/DIVIgor/chirpy/internal/auth"
@@ -12,20 +17,78 @@ import (
 )
 
 // Valid request body with credentials
+//
+// Profile fields are optional. `Handle` is used on account creation and update,
+// `DisplayName` and `Bio` only on update.
 type userAuth struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email       string  `json:"email"`
+	Password    string  `json:"password"`
+	Handle      *string `json:"handle"`
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
 }
 
 // Parsable user model for CRUD operations
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Handle        string    `json:"handle,omitempty"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// Convert DB model to a parsable user
+func userFromDB(user database.User) User {
+	return User{
+		ID:            user.ID,
+		Email:         user.Email,
+		Handle:        user.Handle.String,
+		DisplayName:   user.DisplayName,
+		Bio:           user.Bio,
+		IsChirpyRed:   user.IsChirpyRed,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+}
+
+// Validate optional field from request. Nil field is left unset.
+func parseOptionalField(value *string, validate func(string) (string, error)) (parsed sql.NullString, err error) {
+	if value == nil {
+		return parsed, nil
+	}
+
+	validated, err := validate(*value)
+	if err != nil {
+		return parsed, err
+	}
+
+	return sql.NullString{String: validated, Valid: true}, nil
+}
+
+// Respond with 409 if email or handle is taken by another user, otherwise with 500
+func respWithUserSaveErr(writer http.ResponseWriter, msg string, err error) {
+	constraint, isDuplicate := uniqueViolation(err)
+	switch {
+	case isDuplicate && constraint == "users_handle_key":
+		respWithErr(writer, http.StatusConflict, "Handle is already taken", err)
+	case isDuplicate && (constraint == "users_email_key" || constraint == "users_email_lower_key"):
+		respWithErr(writer, http.StatusConflict, "Email is already taken", err)
+	default:
+		respWithErr(writer, http.StatusInternalServerError, msg, err)
+	}
+}
+
+// Refresh tokens are valid for 60 days since the last rotation
+const refreshTokenTTL time.Duration = 60 * 24 * time.Hour
+
+// Access tokens are valid for 1 hour
+const accessTokenTTL time.Duration = time.Hour
+
 // Success response structure
 type response struct {
 	User
@@ -33,6 +96,12 @@ type response struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// Access and refresh tokens issued on refresh
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
 // Read and decode request body
 func decodeRequest(req *http.Request) (data userAuth, err error) {
 	decoder := json.NewDecoder(req.Body)
@@ -45,56 +114,160 @@ func decodeRequest(req *http.Request) (data userAuth, err error) {
 	return data, err
 }
 
-// Get and check refresh token from headers and generate an access token
+// Validate credentials and profile fields for account creation or full update.
+//
+// Email is returned normalized, profile fields are empty if not provided.
+func validateUserAuth(data userAuth) (email string, handle, displayName, bio sql.NullString, errs validationErrors) {
+	email, err := validateEmail(data.Email)
+	errs.check("email", err)
+	errs.check("password", validatePassword(data.Password))
+
+	handle, err = parseOptionalField(data.Handle, validateHandle)
+	errs.check("handle", err)
+	displayName, err = parseOptionalField(data.DisplayName, validateDisplayName)
+	errs.check("display_name", err)
+	bio, err = parseOptionalField(data.Bio, validateBio)
+	errs.check("bio", err)
+
+	return email, handle, displayName, bio, errs
+}
+
+// Exchange refresh token for a new access token and a new refresh token.
+//
+// The presented refresh token is revoked. Presenting a token that was already rotated
+// means it was most likely stolen, so every token of its family is revoked.
 func (cfg *apiConfig) handlerRefreshAccess(writer http.ResponseWriter, req *http.Request) {
-	type response struct {
-		Token string `json:"token"`
-	}
 	// get refresh token from headers and check refresh token format
 	reqToken, err := auth.GetBearerToken(req.Header, auth.Bearer)
 	if err != nil {
 		respWithErr(writer, http.StatusBadRequest, "Couldn't find token", err)
 		return
 	}
-	// check user
-	user, err := cfg.dbQueries.GetUserFromToken(req.Context(), reqToken)
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't refresh session", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// only one of concurrent requests with the same token can rotate it
+	oldToken, err := qtx.RotateRefreshToken(req.Context(), auth.HashToken(reqToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.checkRefreshTokenReuse(req.Context(), auth.HashToken(reqToken))
 		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user for refresh", err)
 		return
 	}
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't refresh session", err)
+		return
+	}
+
 	// generate new access token
-	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
+	accessToken, accessTokenID, err := cfg.jwtKeys.MakeJWT(oldToken.UserID, accessTokenTTL)
 	if err != nil {
 		respWithErr(writer, http.StatusInternalServerError, "Couldn't create JWT", err)
 		return
 	}
 
-	respJSON(writer, http.StatusOK, response{
-		Token: accessToken,
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't create refresh token", err)
+		return
+	}
+
+	_, err = qtx.SaveRefreshToken(req.Context(), database.SaveRefreshTokenParams{
+		TokenHash:       auth.HashToken(refreshToken),
+		UserID:          oldToken.UserID,
+		FamilyID:        oldToken.FamilyID,
+		ParentTokenHash: sql.NullString{String: oldToken.TokenHash, Valid: true},
+		UserAgent:       oldToken.UserAgent,
+		IpAddress:       oldToken.IpAddress,
+		AccessTokenID:   uuid.NullUUID{UUID: accessTokenID, Valid: true},
+		AccessExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(accessTokenTTL), Valid: true},
+		ExpiresAt:       time.Now().UTC().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't save refresh token", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't refresh session", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, tokenPair{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
 	})
 }
 
+// Revoke the whole token family and its access tokens if the token has already been exchanged for a new one
+func (cfg *apiConfig) checkRefreshTokenReuse(ctx context.Context, tokenHash string) {
+	refreshToken, err := cfg.dbQueries.GetRefreshToken(ctx, tokenHash)
+	if err != nil || !refreshToken.RotatedAt.Valid {
+		return
+	}
+
+	log.Printf("Refresh token reuse detected for user %s, revoking token family %s",
+		refreshToken.UserID, refreshToken.FamilyID)
+	revoked, err := cfg.dbQueries.RevokeTokenFamily(ctx, refreshToken.FamilyID)
+	if err != nil {
+		log.Printf("Couldn't revoke token family %s: %s", refreshToken.FamilyID, err)
+		return
+	}
+
+	// access tokens of the family may be in the hands of whoever reused the token
+	for _, token := range revoked {
+		err = cfg.revocations.RevokeIssued(ctx, token.AccessTokenID, token.UserID, token.AccessExpiresAt)
+		if err != nil {
+			log.Printf("Couldn't revoke access token of token family %s: %s", refreshToken.FamilyID, err)
+		}
+	}
+}
+
 // Get and check refresh token from headers and mark it as revoked in DB
+// along with the last access token issued for it
 func (cfg *apiConfig) handlerRevokeAccess(writer http.ResponseWriter, req *http.Request) {
 	reqToken, err := auth.GetBearerToken(req.Header, auth.Bearer)
 	if err != nil {
 		respWithErr(writer, http.StatusUnauthorized, "Couldn't find token", err)
 		return
 	}
-	err = cfg.dbQueries.RevokeToken(req.Context(), reqToken)
+	refreshToken, err := cfg.dbQueries.RevokeToken(req.Context(), auth.HashToken(reqToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
 	if err != nil {
 		respWithErr(writer, http.StatusUnauthorized, "Couldn't revoke session", err)
 		return
 	}
 
+	err = cfg.revocations.RevokeIssued(req.Context(), refreshToken.AccessTokenID, refreshToken.UserID,
+		refreshToken.AccessExpiresAt)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access token", err)
+		return
+	}
+
 	writer.WriteHeader(http.StatusNoContent)
 }
 
-// Create user with email and password
+// Create user with email, password, and optional handle
 func (cfg *apiConfig) handlerCreateUser(writer http.ResponseWriter, req *http.Request) {
 	data, err := decodeRequest(req)
 	if err != nil {
-		respWithErr(writer, http.StatusInternalServerError, err.Error(), err)
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	email, handle, _, _, errs := validateUserAuth(data)
+	if len(errs) > 0 {
+		respWithValidationErr(writer, errs)
 		return
 	}
 
@@ -105,92 +278,311 @@ func (cfg *apiConfig) handlerCreateUser(writer http.ResponseWriter, req *http.Re
 	}
 
 	user, err := cfg.dbQueries.CreateUser(req.Context(), database.CreateUserParams{
-		Email:          data.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
 	if err != nil {
-		respWithErr(writer, http.StatusInternalServerError, "Couldn't create user", err)
+		respWithUserSaveErr(writer, "Couldn't create user", err)
 		return
 	}
 
+	// the account is created anyway, a new link can be requested later
+	err = cfg.sendVerificationEmail(req.Context(), user)
+	if err != nil {
+		log.Printf("Couldn't send verification email to user %s: %s", user.ID, err)
+	}
+
 	respJSON(writer, http.StatusCreated, response{
-		User: User{
-			ID:          user.ID,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-		},
+		User: userFromDB(user),
 	})
 }
 
-// Update email and/or password with provided credentials and valid token
+// Update email and/or password with provided credentials and valid token.
+// Profile fields (handle, display name, bio) are changed only if provided.
 func (cfg *apiConfig) handlerUpdateUser(writer http.ResponseWriter, req *http.Request) {
-	token, err := auth.GetBearerToken(req.Header, auth.Bearer)
+	userID := requestUserID(req)
+
+	data, err := decodeRequest(req)
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Couldn't find JWT", err)
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	email, handle, displayName, bio, errs := validateUserAuth(data)
+	if len(errs) > 0 {
+		respWithValidationErr(writer, errs)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(data.Password)
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't hash password", err)
 		return
 	}
 
-	data, err := decodeRequest(req)
+	oldUser, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
 	if err != nil {
-		respWithErr(writer, http.StatusInternalServerError, err.Error(), err)
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(data.Password)
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 	if err != nil {
-		respWithErr(writer, http.StatusInternalServerError, "Couldn't hash password", err)
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update credentials", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
 
-	user, err := cfg.dbQueries.UpdateUser(req.Context(), database.UpdateUserParams{
+	user, err := qtx.UpdateUser(req.Context(), database.UpdateUserParams{
 		ID:             userID,
-		Email:          data.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
+		DisplayName:    displayName,
+		Bio:            bio,
 	})
+	if err != nil {
+		respWithUserSaveErr(writer, "Couldn't update credentials", err)
+		return
+	}
+
+	// sessions and access tokens issued before a password change must not outlive it
+	passwordChanged := auth.CheckPasswordHash(data.Password, oldUser.HashedPassword) != nil
+	if passwordChanged {
+		err = qtx.RevokeUserTokens(req.Context(), userID)
+		if err != nil {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		respWithErr(writer, http.StatusInternalServerError, "Couldn't update credentials", err)
 		return
 	}
+	cfg.reverifyChangedEmail(req.Context(), oldUser, user)
+
+	if passwordChanged {
+		err = cfg.revocations.RevokeUser(req.Context(), userID)
+		if err != nil {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+			return
+		}
+	}
 
 	respJSON(writer, http.StatusOK, response{
-		User: User{
-			ID:          user.ID,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-		},
+		User: userFromDB(user),
 	})
 }
 
+// Partially update account of the authenticated user.
+//
+// Only provided fields are changed. Changing email or password requires the current password.
+func (cfg *apiConfig) handlerPatchUser(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	type userPatch struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	data := userPatch{}
+	err := decoder.Decode(&data)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if data.Email == nil && data.Password == nil && data.Handle == nil && data.DisplayName == nil && data.Bio == nil {
+		respWithErr(writer, http.StatusBadRequest, "No fields to update", nil)
+		return
+	}
+
+	params := database.PatchUserParams{ID: userID}
+	errs := validationErrors{}
+	params.Email, err = parseOptionalField(data.Email, validateEmail)
+	errs.check("email", err)
+	if data.Password != nil {
+		errs.check("password", validatePassword(*data.Password))
+	}
+	params.Handle, err = parseOptionalField(data.Handle, validateHandle)
+	errs.check("handle", err)
+	params.DisplayName, err = parseOptionalField(data.DisplayName, validateDisplayName)
+	errs.check("display_name", err)
+	params.Bio, err = parseOptionalField(data.Bio, validateBio)
+	errs.check("bio", err)
+	if len(errs) > 0 {
+		respWithValidationErr(writer, errs)
+		return
+	}
+
+	oldUser, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+
+	// credentials can be changed only with the current password
+	if data.Email != nil || data.Password != nil {
+		err = auth.CheckPasswordHash(data.CurrentPassword, oldUser.HashedPassword)
+		if err != nil {
+			respWithErr(writer, http.StatusForbidden, "Current password is incorrect", err)
+			return
+		}
+	}
+	if data.Password != nil {
+		hashedPassword, err := auth.HashPassword(*data.Password)
+		if err != nil {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't hash password", err)
+			return
+		}
+		params.HashedPassword = sql.NullString{String: hashedPassword, Valid: true}
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	user, err := qtx.PatchUser(req.Context(), params)
+	if err != nil {
+		respWithUserSaveErr(writer, "Couldn't update user", err)
+		return
+	}
+
+	// sessions and access tokens issued before a password change must not outlive it
+	if data.Password != nil {
+		err = qtx.RevokeUserTokens(req.Context(), userID)
+		if err != nil {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't update user", err)
+		return
+	}
+	cfg.reverifyChangedEmail(req.Context(), oldUser, user)
+
+	if data.Password != nil {
+		err = cfg.revocations.RevokeUser(req.Context(), userID)
+		if err != nil {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+			return
+		}
+	}
+
+	respJSON(writer, http.StatusOK, response{
+		User: userFromDB(user),
+	})
+}
+
+// Delete account of the authenticated user after confirming the password.
+//
+// Chirps, sessions, and other user data are removed along with the account.
+func (cfg *apiConfig) handlerDeleteUser(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	type confirmation struct {
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	data := confirmation{}
+	err := decoder.Decode(&data)
+	if err != nil {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+	err = auth.CheckPasswordHash(data.Password, user.HashedPassword)
+	if err != nil {
+		respWithErr(writer, http.StatusForbidden, "Password is incorrect", err)
+		return
+	}
+
+	// sessions are deleted along with the user, so their access tokens have to be revoked first
+	err = cfg.revocations.RevokeUser(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+		return
+	}
+	err = cfg.dbQueries.DeleteUser(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't delete user", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
 // Login with email and password
 func (cfg *apiConfig) handlerLogin(writer http.ResponseWriter, req *http.Request) {
 	data, err := decodeRequest(req)
 	if err != nil {
-		respWithErr(writer, http.StatusInternalServerError, err.Error(), err)
+		respWithErr(writer, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
-	user, err := cfg.dbQueries.GetUser(req.Context(), data.Email)
+	// password rules aren't checked here, since they could change after the account was created
+	email := strings.TrimSpace(data.Email)
+	errs := validationErrors{}
+	if email == "" {
+		errs.check("email", errors.New("Email is required"))
+	}
+	if data.Password == "" {
+		errs.check("password", errors.New("Password is required"))
+	}
+	if len(errs) > 0 {
+		respWithValidationErr(writer, errs)
+		return
+	}
+
+	attempt, wait, err := cfg.loginThrottle.reserve(req.Context(), email, cfg.clientIP(req))
 	if err != nil {
-		respWithErr(writer, http.StatusUnauthorized, "Incorrect email or password", err)
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't check login attempts", err)
 		return
 	}
-	err = auth.CheckPasswordHash(data.Password, user.HashedPassword)
+	if attempt == nil {
+		respWithTooManyAttempts(writer, wait)
+		return
+	}
+
+	// unknown emails count as failures too, so they can't be told apart from locked accounts
+	user, err := cfg.dbQueries.GetUser(req.Context(), email)
+	if err == nil {
+		err = auth.CheckPasswordHash(data.Password, user.HashedPassword)
+	}
 	if err != nil {
+		if throttleErr := attempt.failed(req.Context()); throttleErr != nil {
+			log.Println("Couldn't record failed login:", throttleErr)
+		}
 		respWithErr(writer, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
+	err = attempt.succeeded(req.Context())
+	if err != nil {
+		log.Println("Couldn't reset failed logins:", err)
+	}
 
-	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
+	accessToken, accessTokenID, err := cfg.jwtKeys.MakeJWT(user.ID, accessTokenTTL)
 	if err != nil {
 		respWithErr(writer, http.StatusInternalServerError, "Couldn't create access token", err)
 		return
@@ -202,11 +594,16 @@ func (cfg *apiConfig) handlerLogin(writer http.ResponseWriter, req *http.Request
 		return
 	}
 
-	// Refresh Token should expire in 60 days
-	savedToken, err := cfg.dbQueries.SaveRefreshToken(req.Context(), database.SaveRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().UTC().Add(60 * 24 * time.Hour),
+	// every login starts a new token family, only a digest of the token is stored
+	_, err = cfg.dbQueries.SaveRefreshToken(req.Context(), database.SaveRefreshTokenParams{
+		TokenHash:       auth.HashToken(refreshToken),
+		UserID:          user.ID,
+		FamilyID:        uuid.New(),
+		UserAgent:       req.UserAgent(),
+		IpAddress:       cfg.clientIP(req),
+		AccessTokenID:   uuid.NullUUID{UUID: accessTokenID, Valid: true},
+		AccessExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(accessTokenTTL), Valid: true},
+		ExpiresAt:       time.Now().UTC().Add(refreshTokenTTL),
 	})
 	if err != nil {
 		respWithErr(writer, http.StatusInternalServerError, "Couldn't save refresh token", err)
@@ -214,14 +611,8 @@ func (cfg *apiConfig) handlerLogin(writer http.ResponseWriter, req *http.Request
 	}
 
 	respJSON(writer, http.StatusOK, response{
-		User: User{
-			ID:          user.ID,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-		},
+		User:         userFromDB(user),
 		Token:        accessToken,
-		RefreshToken: savedToken.Token,
+		RefreshToken: refreshToken,
 	})
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/DIVIgor/chirpy/internal/auth"
+	"github.com/DIVIgor/chirpy/internal/database"
+	"github.com/DIVIgor/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+// Verification links are valid for 2 days
+const emailVerificationTTL time.Duration = 48 * time.Hour
+
+// Issue a verification token for the current email of the user and send it by email
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return fmt.Errorf("couldn't create verification token: %w", err)
+	}
+
+	_, err = cfg.dbQueries.SaveEmailVerificationToken(ctx, database.SaveEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't save verification token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/api/users/verify?token=%s", cfg.baseURL, url.QueryEscape(token))
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email",
+		Body: fmt.Sprintf("Open the link below to verify your email:\n\n%s\n\nThe link expires in %s.",
+			link, emailVerificationTTL),
+	})
+}
+
+// Send a verification link if the email of the user has been changed.
+// Failures are only logged since the change itself is already saved.
+func (cfg *apiConfig) reverifyChangedEmail(ctx context.Context, oldUser, user database.User) {
+	if oldUser.Email == user.Email {
+		return
+	}
+
+	err := cfg.sendVerificationEmail(ctx, user)
+	if err != nil {
+		log.Printf("Couldn't send verification email to user %s: %s", user.ID, err)
+	}
+}
+
+// Check if the user is allowed to post chirps according to the email verification policy
+func (cfg *apiConfig) canPost(ctx context.Context, userID uuid.UUID) (allowed bool, err error) {
+	if !cfg.requireVerifiedEmail {
+		return true, nil
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.EmailVerifiedAt.Valid, nil
+}
+
+// Verify email with a token from the verification link
+func (cfg *apiConfig) handlerVerifyEmail(writer http.ResponseWriter, req *http.Request) {
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		respWithErr(writer, http.StatusBadRequest, "Couldn't find token", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	verification, err := qtx.UseEmailVerificationToken(req.Context(), auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusBadRequest, "Invalid or expired token", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't verify email", err)
+		}
+		return
+	}
+
+	// the token is only valid for the email it was sent to
+	user, err := qtx.VerifyUserEmail(req.Context(), database.VerifyUserEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respWithErr(writer, http.StatusBadRequest, "Email has changed since the token was sent", err)
+		} else {
+			respWithErr(writer, http.StatusInternalServerError, "Couldn't verify email", err)
+		}
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+
+	respJSON(writer, http.StatusOK, response{
+		User: userFromDB(user),
+	})
+}
+
+// Send a new verification link to the authenticated user
+func (cfg *apiConfig) handlerResendVerification(writer http.ResponseWriter, req *http.Request) {
+	userID := requestUserID(req)
+
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respWithErr(writer, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respWithErr(writer, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+
+	err = cfg.sendVerificationEmail(req.Context(), user)
+	if err != nil {
+		respWithErr(writer, http.StatusInternalServerError, "Couldn't send verification email", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}